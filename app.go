@@ -5,9 +5,9 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"os/signal"
 	"path/filepath"
 	"slices"
+	"syscall"
 	"time"
 
 	mapset "github.com/deckarep/golang-set/v2"
@@ -16,16 +16,21 @@ import (
 )
 
 type Application[State any] struct {
-	logger  Logger
-	name    string
-	modules Modules
+	logger    Logger
+	name      string
+	modules   Modules
+	selection Selection
 }
 
 func NewApplication[State any](name string, modules Modules, options ...ApplicationOption[State]) *Application[State] {
-	return &Application[State]{
+	a := &Application[State]{
 		name:    name,
 		modules: modules,
 	}
+	for _, option := range options {
+		option(a)
+	}
+	return a
 }
 
 type MainConfig struct {
@@ -48,7 +53,7 @@ func WithCleanupTimeout(timeout time.Duration) MainOption {
 }
 
 func (a *Application[State]) Main(opts ...MainOption) {
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	ctx, cancel := NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
 	cfg := &MainConfig{
@@ -88,10 +93,15 @@ func (a *Application[State]) Run(rootCtx, cleanupCtx context.Context, s *State,
 }
 
 // Glob returns the names of all modules matching any of the provided patterns.
-// Patterns are matched using `filepath.Match`.
+// Patterns are matched using `filepath.Match`, unless pattern is a name of a module (e.g. `build[amd64]`).
 func (a *Application[State]) Glob(patterns ...string) ([]string, error) {
 	result := mapset.NewSet[string]()
 	for _, pattern := range patterns {
+		if _, ok := a.modules[pattern]; ok {
+			result.Add(pattern)
+			continue
+		}
+
 		var matched bool
 
 		for name := range a.modules {
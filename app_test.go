@@ -1,10 +1,17 @@
 package framework_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	framework "github.com/roboslone/go-framework/v2"
 	"github.com/stretchr/testify/assert"
@@ -336,4 +343,832 @@ func TestGlob(t *testing.T) {
 	require.NoError(t, err)
 	slices.Sort(names)
 	require.EqualValues(t, []string{"b1", "b2", "c1", "c2"}, names)
+
+	app = framework.NewApplication[TestState](t.Name(), framework.Modules{
+		"build[amd64]": NewTestModule(),
+		"build[arm64]": NewTestModule(),
+	})
+
+	names, err = app.Glob("build[amd64]")
+	require.NoError(t, err)
+	require.EqualValues(t, []string{"build[amd64]"}, names)
+}
+
+func TestCommandModule_Vars(t *testing.T) {
+	dir := t.TempDir()
+
+	mod := &framework.CommandModule[TestState]{
+		Command: []string{"sh", "-c", "printenv VALUE > ${FILE}"},
+		Dir:     "${DIR}",
+		Env:     []string{"VALUE=${VALUE}"},
+		Vars: map[string]string{
+			"DIR":   dir,
+			"FILE":  "value.txt",
+			"VALUE": "expected",
+		},
+	}
+
+	app := framework.NewApplication[TestState](t.Name(), framework.Modules{
+		"cmd": mod,
+	})
+	require.NoError(t, app.Run(t.Context(), t.Context(), &TestState{}, "cmd"))
+
+	content, err := os.ReadFile(filepath.Join(dir, "value.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "expected\n", string(content))
+}
+
+func TestParseEnv(t *testing.T) {
+	env, err := framework.ParseEnv(strings.NewReader(strings.Join([]string{
+		"# comment",
+		"",
+		"export A=1",
+		`B="two\tlines" # comment`,
+		"C='three # literal'",
+		"D = four # comment",
+		"E=",
+	}, "\n")))
+	require.NoError(t, err)
+	require.EqualValues(t, []string{"A=1", "B=two\tlines", "C=three # literal", "D=four", "E="}, env)
+
+	_, err = framework.ParseEnv(strings.NewReader(`A="unterminated`))
+	require.ErrorContains(t, err, "line 1")
+}
+
+func TestCommandModule_Env(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte("FROM_FILE=file\nOVERRIDDEN=file\n"), 0o600))
+	t.Setenv("INHERITED", "inherited")
+	t.Setenv("PASSED", "passed")
+
+	mod := &framework.CommandModule[TestState]{
+		Command:  []string{"sh", "-c", "env > env.txt"},
+		Dir:      dir,
+		Env:      []string{"OVERRIDDEN=env"},
+		EnvFiles: []string{".env", "missing.env"},
+		CleanEnv: true,
+		PassEnv:  []string{"PASS*"},
+	}
+
+	app := framework.NewApplication[TestState](t.Name(), framework.Modules{
+		"cmd": mod,
+	})
+	require.NoError(t, app.Run(t.Context(), t.Context(), &TestState{}, "cmd"))
+
+	content, err := os.ReadFile(filepath.Join(dir, "env.txt"))
+	require.NoError(t, err)
+
+	env := strings.Split(string(content), "\n")
+	require.Contains(t, env, "PASSED=passed")
+	require.Contains(t, env, "FROM_FILE=file")
+	require.Contains(t, env, "OVERRIDDEN=env")
+	require.NotContains(t, env, "INHERITED=inherited")
+}
+
+func TestCommandModule_Outputs(t *testing.T) {
+	dir := t.TempDir()
+	outputs := framework.NewOutputs()
+
+	app := framework.NewApplication[TestState](t.Name(), framework.Modules{
+		"build": &framework.CommandModule[TestState]{
+			Command: []string{"sh", "-c", "echo IMAGE=app:42 >> ${FEXEC_OUTPUT} && echo ' sha '"},
+			Output:  "SHA",
+			Outputs: outputs,
+		},
+		"deploy": &framework.CommandModule[TestState]{
+			Command:   []string{"sh", "-c", "echo ${build.IMAGE} ${build.SHA} > deployed.txt"},
+			Dir:       dir,
+			DependsOn: []string{"build"},
+			Outputs:   outputs,
+		},
+		"typo": &framework.CommandModule[TestState]{
+			Command:   []string{"sh", "-c", "echo ${build.IMAGES} > typo.txt"},
+			Dir:       dir,
+			DependsOn: []string{"build"},
+			Outputs:   outputs,
+		},
+		"script": &framework.CommandModule[TestState]{
+			Script:    "echo ${build.SHA} ${build.TAG} > script.txt",
+			Dir:       dir,
+			DependsOn: []string{"build"},
+			Outputs:   outputs,
+		},
+	})
+	require.NoError(t, app.Run(t.Context(), t.Context(), &TestState{}, "deploy"))
+
+	require.Equal(t, map[string]string{"IMAGE": "app:42", "SHA": "sha"}, outputs.All("build"))
+
+	content, err := os.ReadFile(filepath.Join(dir, "deployed.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "app:42 sha\n", string(content))
+
+	// references to values, that dependencies didn't publish, fail before the command runs
+	for name, key := range map[string]string{"typo": "IMAGES", "script": "TAG"} {
+		err = app.Run(t.Context(), t.Context(), &TestState{}, name)
+		require.ErrorContains(t, err, fmt.Sprintf(`output %q is not published by "build"`, key))
+		require.NoFileExists(t, filepath.Join(dir, name+".txt"))
+	}
+}
+
+func TestCommandModule_OutputsOfSkipped(t *testing.T) {
+	dir := t.TempDir()
+	outputs := framework.NewOutputs()
+	events := &eventRecorder{}
+
+	app := framework.NewApplication[TestState](t.Name(), framework.Modules{
+		"build": &framework.CommandModule[TestState]{
+			Command: []string{"sh", "-c", "echo IMAGE=app:42 >> ${FEXEC_OUTPUT}"},
+			SkipIf:  &framework.Condition{Probe: "test -n \"$SKIP\""},
+			Outputs: outputs,
+		},
+		"deploy": &framework.CommandModule[TestState]{
+			Steps: []framework.CommandStep{
+				{Command: []string{"sh", "-c", "echo \"[${build.IMAGE}]\" > command.txt"}},
+				{Script: `echo "[${build.IMAGE}]" > script.txt`},
+			},
+			Dir:       dir,
+			DependsOn: []string{"build"},
+			Outputs:   outputs,
+			Reporter:  events,
+		},
+	})
+
+	// skipped dependencies count as satisfied, their outputs are empty
+	t.Setenv("SKIP", "1")
+	require.NoError(t, app.Run(t.Context(), t.Context(), &TestState{}, "deploy"))
+	for _, name := range []string{"command.txt", "script.txt"} {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		require.Equal(t, "[]\n", string(content), name)
+	}
+	var warnings []string
+	for _, e := range events.events {
+		if w, ok := e.(*framework.WarningEvent); ok {
+			warnings = append(warnings, w.Message)
+		}
+	}
+	require.Equal(t, []string{`output "IMAGE" of "build" is empty, as "build" didn't run`}, warnings)
+
+	// once the dependency runs, its outputs are substituted again
+	t.Setenv("SKIP", "")
+	require.NoError(t, app.Run(t.Context(), t.Context(), &TestState{}, "deploy"))
+	content, err := os.ReadFile(filepath.Join(dir, "script.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "[app:42]\n", string(content))
+}
+
+func TestCommandModule_Script(t *testing.T) {
+	dir := t.TempDir()
+
+	mod := &framework.CommandModule[TestState]{
+		Script: "for v in a b; do echo \"$v ${VALUE}\" >> script.txt; done",
+		Dir:    dir,
+		Vars:   map[string]string{"VALUE": "value"},
+	}
+
+	app := framework.NewApplication[TestState](t.Name(), framework.Modules{
+		"cmd": mod,
+	})
+	require.NoError(t, app.Run(t.Context(), t.Context(), &TestState{}, "cmd"))
+
+	content, err := os.ReadFile(filepath.Join(dir, "script.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "a value\nb value\n", string(content))
+
+	// positional parameters, awk fields and unknown references are left to the shell
+	mod.Script = `set -- first; echo "a b c" | awk '{print $1, $NF}' > awk.txt; echo "$1 ${1} ${UNKNOWN_VAR:-}$VALUE" > args.txt`
+	require.NoError(t, app.Run(t.Context(), t.Context(), &TestState{}, "cmd"))
+	for name, expected := range map[string]string{"awk.txt": "a c\n", "args.txt": "first first value\n"} {
+		content, err = os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		require.Equal(t, expected, string(content), name)
+	}
+
+	mod.Script = "false; echo unreachable"
+	require.ErrorContains(t, app.Run(t.Context(), t.Context(), &TestState{}, "cmd"), "exit status 1")
+}
+
+func TestCommandModule_Steps(t *testing.T) {
+	dir := t.TempDir()
+
+	mod := &framework.CommandModule[TestState]{
+		Dir: dir,
+		Steps: []framework.CommandStep{
+			{Name: "first", Command: []string{"touch", "first"}},
+			{Name: "second", Script: "test -f first && touch second"},
+		},
+	}
+
+	app := framework.NewApplication[TestState](t.Name(), framework.Modules{
+		"cmd": mod,
+	})
+	require.NoError(t, app.Run(t.Context(), t.Context(), &TestState{}, "cmd"))
+	require.FileExists(t, filepath.Join(dir, "second"))
+
+	mod.Dir = t.TempDir()
+	mod.Steps = []framework.CommandStep{
+		{Script: "exit 3"},
+		{Command: []string{"touch", "unreachable"}},
+	}
+	require.ErrorContains(t, app.Run(t.Context(), t.Context(), &TestState{}, "cmd"), "exit status 3")
+	require.NoFileExists(t, filepath.Join(mod.Dir, "unreachable"))
+}
+
+func TestCommandModule_Conditions(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("CONDITION", "set")
+
+	touch := func(name string, when, skipIf *framework.Condition, deps ...string) *framework.CommandModule[TestState] {
+		return &framework.CommandModule[TestState]{
+			Command:   []string{"touch", name},
+			Dir:       dir,
+			When:      when,
+			SkipIf:    skipIf,
+			DependsOn: deps,
+		}
+	}
+
+	app := framework.NewApplication[TestState](t.Name(), framework.Modules{
+		"env-set":     touch("env-set", &framework.Condition{Env: map[string]string{"CONDITION": ""}}, nil),
+		"env-equal":   touch("env-equal", &framework.Condition{Env: map[string]string{"CONDITION": "other"}}, nil),
+		"file-exists": touch("file-exists", &framework.Condition{Files: []string{"env-set"}}, nil, "env-set"),
+		"probe":       touch("probe", nil, &framework.Condition{Probe: "true"}),
+		"dependent":   touch("dependent", nil, nil, "env-equal", "probe"),
+	})
+	require.NoError(t, app.Run(t.Context(), t.Context(), &TestState{}, "file-exists", "dependent"))
+
+	require.FileExists(t, filepath.Join(dir, "env-set"))
+	require.NoFileExists(t, filepath.Join(dir, "env-equal"))
+	require.FileExists(t, filepath.Join(dir, "file-exists"))
+	require.NoFileExists(t, filepath.Join(dir, "probe"))
+	require.FileExists(t, filepath.Join(dir, "dependent"))
+}
+
+func TestLogReporter(t *testing.T) {
+	dir := t.TempDir()
+	logs, err := framework.NewLogReporter(dir)
+	require.NoError(t, err)
+
+	app := framework.NewApplication[TestState](t.Name(), framework.Modules{
+		"ns:cmd": &framework.CommandModule[TestState]{
+			Script:   "echo out; echo err >&2",
+			Reporter: logs,
+		},
+	})
+	require.NoError(t, app.Run(t.Context(), t.Context(), &TestState{}, "ns:cmd"))
+	require.NoError(t, logs.Close())
+
+	content, err := os.ReadFile(filepath.Join(dir, "ns_cmd.log"))
+	require.NoError(t, err)
+	require.Contains(t, string(content), "[stdout] out\n")
+	require.Contains(t, string(content), "[stderr] err\n")
+	require.Contains(t, string(content), "succeeded in")
+
+	content, err = os.ReadFile(filepath.Join(dir, framework.LogIndexName))
+	require.NoError(t, err)
+
+	index := framework.LogIndex{}
+	require.NoError(t, json.Unmarshal(content, &index))
+	require.Len(t, index.Modules, 1)
+	require.Equal(t, "ns:cmd", index.Modules[0].Module)
+	require.Equal(t, "ns_cmd.log", index.Modules[0].Log)
+	require.Equal(t, framework.StatusSucceeded, index.Modules[0].Status)
+
+	// modules are recorded in the index even if their log can't be created
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "broken.log"), 0o755))
+	logs, err = framework.NewLogReporter(dir)
+	require.NoError(t, err)
+
+	app = framework.NewApplication[TestState](t.Name(), framework.Modules{
+		"broken": &framework.CommandModule[TestState]{
+			Script:   "echo out; exit 1",
+			Reporter: logs,
+		},
+	})
+	require.Error(t, app.Run(t.Context(), t.Context(), &TestState{}, "broken"))
+	require.ErrorContains(t, logs.Close(), "creating log file")
+
+	content, err = os.ReadFile(filepath.Join(dir, framework.LogIndexName))
+	require.NoError(t, err)
+
+	index = framework.LogIndex{}
+	require.NoError(t, json.Unmarshal(content, &index))
+	require.Len(t, index.Modules, 1)
+	require.Equal(t, "broken", index.Modules[0].Module)
+	require.Empty(t, index.Modules[0].Log)
+	require.Contains(t, index.Modules[0].LogError, "creating log file")
+	require.Equal(t, framework.StatusFailed, index.Modules[0].Status)
+	require.Equal(t, "exit status 1", index.Modules[0].Error)
+	require.NotZero(t, index.Modules[0].Duration)
+}
+
+func TestCommandModule_Signals(t *testing.T) {
+	dir := t.TempDir()
+
+	mod := &framework.CommandModule[TestState]{
+		Dir:       dir,
+		KillGrace: 200 * time.Millisecond,
+	}
+	app := framework.NewApplication[TestState](t.Name(), framework.Modules{
+		"cmd": mod,
+	})
+
+	run := func(script string, cause error) {
+		ctx, cancel := context.WithCancelCause(t.Context())
+		go func() {
+			time.Sleep(300 * time.Millisecond)
+			cancel(cause)
+		}()
+
+		mod.Script = script
+		started := time.Now()
+		require.Error(t, app.Run(ctx, ctx, &TestState{}, "cmd"))
+		require.Less(t, time.Since(started), 5*time.Second)
+	}
+
+	// signal is forwarded to the whole process group
+	run(`(trap 'echo TERM > term.txt; exit 1' TERM; while :; do sleep 0.05; done) & wait`, context.Canceled)
+	content, err := os.ReadFile(filepath.Join(dir, "term.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "TERM\n", string(content))
+
+	// signal from the cause is used, processes ignoring it are killed after grace period
+	run(`trap 'echo INT > int.txt; exit 1' INT; sleep 60 & wait`, &framework.SignalCause{Signal: os.Interrupt})
+	content, err = os.ReadFile(filepath.Join(dir, "int.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "INT\n", string(content))
+}
+
+func TestCommandModule_TTY(t *testing.T) {
+	dir := t.TempDir()
+	logs, err := framework.NewLogReporter(dir)
+	require.NoError(t, err)
+
+	outputs := framework.NewOutputs()
+	app := framework.NewApplication[TestState](t.Name(), framework.Modules{
+		"cmd": &framework.CommandModule[TestState]{
+			Script:   `test -t 1 && test -t 2 && printf '10%%\r50%%\r100%%\r\n' && printf '\033[31mred\033[0m\n'`,
+			TTY:      true,
+			Output:   "OUT",
+			Outputs:  outputs,
+			Reporter: logs,
+		},
+	})
+	require.NoError(t, app.Run(t.Context(), t.Context(), &TestState{}, "cmd"))
+	require.NoError(t, logs.Close())
+
+	out, _ := outputs.Get("cmd", "OUT")
+	require.Contains(t, out, "\x1b[31mred\x1b[0m")
+
+	content, err := os.ReadFile(filepath.Join(dir, "cmd.log"))
+	require.NoError(t, err)
+	require.Contains(t, string(content), "[stdout] 100%\n")
+	require.NotContains(t, string(content), "[stdout] 50%")
+}
+
+func TestCommandModule_Resolve(t *testing.T) {
+	mod := &framework.CommandModule[TestState]{
+		Steps: []framework.CommandStep{
+			{Command: []string{"echo", "${VALUE}", "${FROM_ENV}"}},
+			{Script: "echo ${VALUE} ${FROM_SHELL}"},
+		},
+		Dir:  "/tmp/${VALUE}",
+		Env:  []string{"FROM_ENV=env-${VALUE}"},
+		Vars: map[string]string{"VALUE": "value"},
+	}
+
+	r, err := mod.Resolve(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, "/tmp/value", r.Dir)
+	require.Equal(t, []string{"FROM_ENV=env-value"}, r.Env)
+	require.Equal(t, []framework.CommandStep{
+		{Command: []string{"echo", "value", "env-value"}},
+		{Script: "echo value ${FROM_SHELL}"},
+	}, r.Steps)
+}
+
+func TestCommandModule_Validate(t *testing.T) {
+	require.NoError(t, (&framework.CommandModule[TestState]{Command: []string{"true"}}).Validate())
+
+	err := (&framework.CommandModule[TestState]{
+		Command: []string{"true"},
+		Steps:   []framework.CommandStep{{Name: "empty"}},
+		PassEnv: []string{"PATH"},
+	}).Validate()
+	require.ErrorContains(t, err, "steps can't be combined with command or script")
+	require.ErrorContains(t, err, "step empty: either command or script must be set")
+	require.ErrorContains(t, err, "pass_env has no effect without clean_env")
+
+	require.ErrorContains(t, (&framework.CommandModule[TestState]{}).Validate(), "nothing to run")
+}
+
+func TestTopology_CriticalPath(t *testing.T) {
+	app := framework.NewApplication[TestState](t.Name(), framework.Modules{
+		"install": NewTestModule(),
+		"lint":    NewTestModule("install"),
+		"test":    NewTestModule("install"),
+		"build":   NewTestModule("lint"),
+		"ci":      NewTestModule("build", "test"),
+	})
+	topology, err := app.BuildTopology(t.Context(), "ci")
+	require.NoError(t, err)
+
+	cost := map[string]time.Duration{"install": 1, "lint": 2, "test": 5, "build": 1}
+	path, total := topology.CriticalPath(func(module string) time.Duration { return cost[module] })
+	require.Equal(t, []string{"install", "test"}, path)
+	require.Equal(t, time.Duration(6), total)
+
+	cost["build"] = 3
+	path, total = topology.CriticalPath(func(module string) time.Duration { return cost[module] })
+	require.Equal(t, []string{"install", "lint", "build"}, path)
+	require.Equal(t, time.Duration(6), total)
+}
+
+func TestSelection(t *testing.T) {
+	modules := framework.Modules{
+		"install": NewTestModule(),
+		"lint":    NewTestModule("install"),
+		"test":    NewTestModule("install"),
+		"build":   NewTestModule("test"),
+		"ci":      NewTestModule("lint", "build"),
+	}
+
+	for _, tc := range []struct {
+		selection framework.Selection
+		requested []string
+		expected  []string
+	}{
+		{framework.Selection{}, []string{"build"}, []string{"build", "install", "test"}},
+		{framework.Selection{Only: true}, []string{"build", "lint"}, []string{"build", "lint"}},
+		{framework.Selection{DependenciesOnly: true}, []string{"build"}, []string{"install", "test"}},
+		{framework.Selection{From: []string{"test"}}, []string{"ci"}, []string{"build", "ci", "test"}},
+		{framework.Selection{From: []string{"test"}, Only: true}, []string{"ci", "lint", "test"}, []string{"ci", "test"}},
+		{framework.Selection{Modules: []string{"test", "build", "ci"}}, []string{"ci"}, []string{"build", "ci", "test"}},
+		{framework.Selection{Modules: []string{"test", "build", "ci"}, DependenciesOnly: true}, []string{"ci"}, []string{"build", "test"}},
+	} {
+		app := framework.NewApplication(t.Name(), modules, framework.WithSelection[TestState](tc.selection))
+		topology, err := app.BuildTopology(t.Context(), tc.requested...)
+		require.NoError(t, err)
+
+		selected := slices.Clone(topology.OrderedModuleNames)
+		slices.Sort(selected)
+		require.Equal(t, tc.expected, selected, "%+v %v", tc.selection, tc.requested)
+
+		for _, name := range selected {
+			for _, d := range topology.FullDependencies[name] {
+				require.Contains(t, selected, d)
+			}
+		}
+	}
+
+	app := framework.NewApplication(t.Name(), modules, framework.WithSelection[TestState](framework.Selection{DependenciesOnly: true}))
+	_, err := app.BuildTopology(t.Context(), "install")
+	require.ErrorContains(t, err, "no modules selected")
+}
+
+func TestParseDiagnostics(t *testing.T) {
+	output := "ok\n./sub/a.go:3:5: undefined: x\n\x1b[1mb.c:1: warning: unused\x1b[0m\n12:00:01: not a file\n    c_test.go:7: error: failed\n"
+	require.Equal(t, []framework.Diagnostic{
+		{File: "./sub/a.go", Line: 3, Column: 5, Message: "undefined: x"},
+		{File: "b.c", Line: 1, Message: "unused", Warning: true},
+		{File: "c_test.go", Line: 7, Message: "failed"},
+	}, framework.ParseDiagnostics([]byte(output)))
+}
+
+func TestCIReporter(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("GITHUB_WORKSPACE", filepath.Dir(dir))
+
+	out := &bytes.Buffer{}
+	reporter := framework.NewCIReporter(framework.CIGitHub)
+	reporter.Out = out
+
+	verbose := framework.NewCIReporter(framework.CIGitHub)
+	verbose.Out, verbose.Verbose = out, true
+
+	app := framework.NewApplication[TestState](t.Name(), framework.Modules{
+		"ok": &framework.CommandModule[TestState]{
+			Command:  []string{"echo", "fine"},
+			Reporter: reporter,
+		},
+		"verbose": &framework.CommandModule[TestState]{
+			Command:   []string{"echo", "fine"},
+			DependsOn: []string{"ok"},
+			Reporter:  verbose,
+		},
+		"lint": &framework.CommandModule[TestState]{
+			Dir:       dir,
+			Script:    "echo 'a.go:3:5: bad, very bad'; echo \"$PWD/b.go:1: worse\"; exit 1",
+			DependsOn: []string{"verbose"},
+			Reporter:  reporter,
+			// annotations point to the original dir, not the copy
+			WorkdirCopy: true,
+		},
+	})
+	require.Error(t, app.Run(t.Context(), t.Context(), &TestState{}, "lint"))
+
+	lines := strings.Split(out.String(), "\n")
+	require.Regexp(t, `^✓ ok \S+$`, lines[0], "output of successful modules is only printed when verbose")
+	require.Regexp(t, `^::group::✓ verbose `, lines[1])
+	require.Equal(t, []string{"$ echo fine", "fine", "::endgroup::"}, lines[2:5])
+	require.Regexp(t, `^::group::❌ lint `, lines[5])
+	require.Contains(t, lines, "::endgroup::")
+
+	base := filepath.Base(dir)
+	require.Contains(t, lines, "::error file="+base+"/a.go,line=3,col=5,title=lint::bad, very bad")
+	require.Contains(t, lines, "::error file="+base+"/b.go,line=1,title=lint::worse")
+	require.Contains(t, lines, "::error title=lint::lint failed: exit status 1")
+}
+
+func TestCommandModule_Secrets(t *testing.T) {
+	dir := t.TempDir()
+	logs, err := framework.NewLogReporter(dir)
+	require.NoError(t, err)
+
+	outputs := framework.NewOutputs()
+	app := framework.NewApplication[TestState](t.Name(), framework.Modules{
+		"cmd": &framework.CommandModule[TestState]{
+			// secret is split across writes
+			Script:   `set -x; printf 'tok'; sleep 0.1; printf 'en-1234 key=abc\n' >&1; echo "$TOKEN"; echo "$SHORT"`,
+			Env:      []string{"TOKEN=token-1234", "SHORT=ab"},
+			Secrets:  []string{"TOKEN", "SHORT"},
+			Mask:     []string{`key=\w+`},
+			Output:   "OUT",
+			Outputs:  outputs,
+			Reporter: logs,
+		},
+	})
+	require.NoError(t, app.Run(t.Context(), t.Context(), &TestState{}, "cmd"))
+	require.NoError(t, logs.Close())
+
+	content, err := os.ReadFile(filepath.Join(dir, "cmd.log"))
+	require.NoError(t, err)
+	require.NotContains(t, string(content), "token-1234")
+	require.NotContains(t, string(content), "key=abc")
+	require.Contains(t, string(content), "[stdout] *** ***\n")
+	require.Contains(t, string(content), "secret SHORT is too short to be masked")
+
+	// published values are kept intact
+	value, _ := outputs.Get("cmd", "OUT")
+	require.Contains(t, value, "token-1234")
+
+	// returned errors are masked as well
+	app = framework.NewApplication[TestState](t.Name(), framework.Modules{
+		"cmd": &framework.CommandModule[TestState]{
+			Command: []string{"./$TOKEN"},
+			Env:     []string{"TOKEN=token-1234"},
+			Secrets: []string{"TOKEN"},
+		},
+	})
+	err = app.Run(t.Context(), t.Context(), &TestState{}, "cmd")
+	require.ErrorContains(t, err, "./***")
+	require.NotContains(t, err.Error(), "token-1234")
+
+	mod := &framework.CommandModule[TestState]{Command: []string{"true"}, Mask: []string{"("}}
+	require.ErrorContains(t, mod.Validate(), "mask")
+}
+
+func TestParseByteSize(t *testing.T) {
+	for s, expected := range map[string]framework.ByteSize{
+		"1024":    1024,
+		"512MiB":  512 << 20,
+		"1.5Gi":   3 << 29,
+		"2GB":     2e9,
+		"10 k":    10e3,
+		"100b":    100,
+		"0.5 tib": 1 << 39,
+	} {
+		size, err := framework.ParseByteSize(s)
+		require.NoError(t, err, s)
+		require.Equal(t, expected, size, s)
+	}
+
+	for _, s := range []string{"", "MiB", "-1G", "1 PB"} {
+		_, err := framework.ParseByteSize(s)
+		require.Error(t, err, s)
+	}
+
+	require.Equal(t, "1.5GiB", framework.ByteSize(3<<29).String())
+	require.Equal(t, "100B", framework.ByteSize(100).String())
+}
+
+func TestCommandModule_Usage(t *testing.T) {
+	dir := t.TempDir()
+	logs, err := framework.NewLogReporter(dir)
+	require.NoError(t, err)
+
+	app := framework.NewApplication[TestState](t.Name(), framework.Modules{
+		"ok": &framework.CommandModule[TestState]{
+			Script:   "i=0; while [ $i -lt 10000 ]; do i=$((i+1)); done",
+			Reporter: logs,
+		},
+		"limited": &framework.CommandModule[TestState]{
+			Command:   []string{"true"},
+			MaxMemory: 1,
+			Reporter:  logs,
+		},
+	})
+	require.NoError(t, app.Run(t.Context(), t.Context(), &TestState{}, "ok"))
+	err = app.Run(t.Context(), t.Context(), &TestState{}, "limited")
+	require.ErrorContains(t, err, "max memory exceeded")
+	require.NoError(t, logs.Close())
+
+	content, err := os.ReadFile(filepath.Join(dir, framework.LogIndexName))
+	require.NoError(t, err)
+
+	index := framework.LogIndex{}
+	require.NoError(t, json.Unmarshal(content, &index))
+	require.Len(t, index.Modules, 2)
+	for _, m := range index.Modules {
+		require.NotNil(t, m.Usage, m.Module)
+		require.Positive(t, m.Usage.MaxRSS, m.Module)
+	}
+}
+
+func TestCommandModule_TmpDir(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "tmp.txt")
+
+	mod := &framework.CommandModule[TestState]{
+		Script: `test -d "$FEXEC_TMP" && echo "$CACHE" > ` + out,
+		Env:    []string{"CACHE=${FEXEC_TMP}/cache"},
+		TmpDir: true,
+	}
+	app := framework.NewApplication[TestState](t.Name(), framework.Modules{"cmd": mod})
+	require.NoError(t, app.Run(t.Context(), t.Context(), &TestState{}, "cmd"))
+
+	content, err := os.ReadFile(out)
+	require.NoError(t, err)
+	cache := strings.TrimSpace(string(content))
+	require.Equal(t, "cache", filepath.Base(cache))
+	require.NoDirExists(t, filepath.Dir(cache), "temp dir is removed once the command finishes")
+
+	// failed commands don't leave temp dirs behind
+	mod.Script = `echo "$FEXEC_TMP" > ` + out + `; exit 1`
+	require.Error(t, app.Run(t.Context(), t.Context(), &TestState{}, "cmd"))
+	content, err = os.ReadFile(out)
+	require.NoError(t, err)
+	require.NoDirExists(t, strings.TrimSpace(string(content)))
+}
+
+func TestCommandModule_WorkdirCopy(t *testing.T) {
+	repo, out := t.TempDir(), t.TempDir()
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = repo
+		output, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(output))
+		return string(output)
+	}
+
+	require.NoError(t, os.MkdirAll(filepath.Join(repo, "sub"), 0o755))
+	for name, content := range map[string]string{
+		".gitignore":    "ignored.txt\n",
+		"committed.txt": "committed\n",
+		"deleted.txt":   "deleted\n",
+		"sub/file.txt":  "file\n",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(repo, name), []byte(content), 0o644))
+	}
+	git("init", "--quiet")
+	git("add", ".")
+	git("commit", "--quiet", "-m", "initial")
+
+	// uncommitted changes are copied, ignored files are not
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "committed.txt"), []byte("modified\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "untracked.txt"), []byte("untracked\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(repo, "ignored.txt"), []byte("ignored\n"), 0o644))
+	require.NoError(t, os.Remove(filepath.Join(repo, "deleted.txt")))
+
+	mod := &framework.CommandModule[TestState]{
+		Script:      `cat file.txt ../committed.txt ../untracked.txt > ${OUT}/content; ls .. > ${OUT}/ls; pwd > ${OUT}/pwd; echo changed > file.txt`,
+		Dir:         filepath.Join(repo, "sub"),
+		Vars:        map[string]string{"OUT": out},
+		WorkdirCopy: true,
+	}
+	app := framework.NewApplication[TestState](t.Name(), framework.Modules{"cmd": mod})
+	require.NoError(t, app.Run(t.Context(), t.Context(), &TestState{}, "cmd"))
+
+	read := func(name string) string {
+		content, err := os.ReadFile(filepath.Join(out, name))
+		require.NoError(t, err)
+		return string(content)
+	}
+	require.Equal(t, "file\nmodified\nuntracked\n", read("content"))
+	require.Equal(t, "committed.txt\nsub\nuntracked.txt\n", read("ls"))
+	require.Equal(t, "sub", filepath.Base(strings.TrimSpace(read("pwd"))))
+
+	// the tree is intact, the copy and its worktree are removed
+	content, err := os.ReadFile(filepath.Join(repo, "sub", "file.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "file\n", string(content))
+	require.NoDirExists(t, filepath.Dir(strings.TrimSpace(read("pwd"))))
+	require.Len(t, strings.Split(strings.TrimSpace(git("worktree", "list")), "\n"), 1)
+
+	// so are copies of failed commands
+	mod.Script = `pwd > ${OUT}/pwd; exit 1`
+	require.Error(t, app.Run(t.Context(), t.Context(), &TestState{}, "cmd"))
+	require.NoDirExists(t, filepath.Dir(strings.TrimSpace(read("pwd"))))
+	require.Len(t, strings.Split(strings.TrimSpace(git("worktree", "list")), "\n"), 1)
+}
+
+func TestCommandModule_Stdin(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "input.txt"), []byte("from file\n"), 0o644))
+
+	app := framework.NewApplication[TestState](t.Name(), framework.Modules{
+		"text": &framework.CommandModule[TestState]{
+			Script: "cat > text.txt",
+			Dir:    dir,
+			Stdin:  &framework.Input{Text: "y\n"},
+		},
+		"file": &framework.CommandModule[TestState]{
+			Script: "cat > file.txt",
+			Dir:    dir,
+			Vars:   map[string]string{"NAME": "input"},
+			Stdin:  &framework.Input{File: "${NAME}.txt"},
+		},
+		"interactive": &framework.CommandModule[TestState]{
+			Script:      "cat > interactive.txt",
+			Dir:         dir,
+			Stdin:       &framework.Input{Text: "interactive\n"},
+			Interactive: true,
+		},
+	})
+	require.NoError(t, app.Run(t.Context(), t.Context(), &TestState{}, "text", "file", "interactive"))
+
+	for name, expected := range map[string]string{
+		"text.txt":        "y\n",
+		"file.txt":        "from file\n",
+		"interactive.txt": "interactive\n",
+	} {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		require.Equal(t, expected, string(content), name)
+	}
+
+	var input framework.Input
+	require.NoError(t, input.UnmarshalText([]byte("inherit")))
+	require.Equal(t, framework.Input{Inherit: true}, input)
+
+	mod := &framework.CommandModule[TestState]{
+		Command:     []string{"true"},
+		Stdin:       &framework.Input{Text: "y", Inherit: true},
+		Interactive: true,
+		TTY:         true,
+	}
+	err := mod.Validate()
+	require.ErrorContains(t, err, "mutually exclusive")
+	require.ErrorContains(t, err, "stdin has no effect with tty")
+	require.ErrorContains(t, err, "interactive and tty")
+}
+
+func TestTerminal(t *testing.T) {
+	terminal := framework.NewTerminal()
+
+	var printed []string
+	record := func(s string) func() {
+		return func() { printed = append(printed, s) }
+	}
+
+	terminal.Print(record("before"))
+	release, err := terminal.Acquire(t.Context())
+	require.NoError(t, err)
+
+	terminal.Print(record("deferred"))
+	require.False(t, terminal.TryPrint(record("skipped")))
+	require.Equal(t, []string{"before"}, printed)
+
+	// the terminal is exclusive
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	_, err = terminal.Acquire(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	release()
+	release()
+	require.Equal(t, []string{"before", "deferred"}, printed)
+	require.True(t, terminal.TryPrint(record("after")))
+	require.Equal(t, []string{"before", "deferred", "after"}, printed)
+}
+
+func TestCommandModule_StartErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, mod := range map[string]*framework.CommandModule[TestState]{
+		"env file":  {Command: []string{"true"}, EnvFiles: []string{dir}},
+		"mask":      {Command: []string{"true"}, Mask: []string{"("}},
+		"condition": {Command: []string{"true"}, Dir: dir, When: &framework.Condition{Changed: []string{"*"}}},
+	} {
+		events := &eventRecorder{}
+		mod.Reporter = events
+
+		app := framework.NewApplication[TestState](t.Name(), framework.Modules{"cmd": mod})
+		err := app.Run(t.Context(), t.Context(), &TestState{}, "cmd")
+		require.Error(t, err, name)
+
+		finish := events.finish()
+		require.NotNil(t, finish, name)
+		require.Equal(t, framework.StatusFailed, finish.Status, name)
+		require.Error(t, finish.Err, name)
+	}
 }
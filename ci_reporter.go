@@ -0,0 +1,284 @@
+package framework
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CIProvider is a CI system, which log folding and annotations are supported by `CIReporter`.
+type CIProvider string
+
+const (
+	CIGitHub   CIProvider = "github"
+	CIGitLab   CIProvider = "gitlab"
+	CITeamCity CIProvider = "teamcity"
+)
+
+// maxAnnotations limits the number of diagnostics reported per module.
+const maxAnnotations = 50
+
+var (
+	diagnosticPattern = regexp.MustCompile(`^\s*([^\s:]*[./][^\s:]*):(\d+)(?::(\d+))?:\s+(.+)$`)
+	ansiPattern       = regexp.MustCompile("\x1b\\[[0-9;?]*[A-Za-z]")
+	sectionNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+)
+
+// DetectCI returns CI provider the process is run by, based on environment. Empty string is returned outside of CI.
+func DetectCI() CIProvider {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return CIGitHub
+	case os.Getenv("GITLAB_CI") == "true":
+		return CIGitLab
+	case os.Getenv("TEAMCITY_VERSION") != "":
+		return CITeamCity
+	default:
+		return ""
+	}
+}
+
+// Diagnostic is a `file:line:col: message` line found in command output.
+type Diagnostic struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+	Warning bool
+}
+
+// ParseDiagnostics finds `file:line[:col]: message` lines in command output, e.g. reported by compilers and linters.
+// Messages starting with `warning:` are marked as warnings, `error:` prefix is trimmed.
+func ParseDiagnostics(output []byte) []Diagnostic {
+	var result []Diagnostic
+	for _, line := range strings.Split(ansiPattern.ReplaceAllString(string(output), ""), "\n") {
+		m := diagnosticPattern.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if m == nil {
+			continue
+		}
+
+		d := Diagnostic{File: m[1], Message: m[4]}
+		d.Line, _ = strconv.Atoi(m[2])
+		d.Column, _ = strconv.Atoi(m[3])
+		if msg, ok := strings.CutPrefix(d.Message, "warning: "); ok {
+			d.Message, d.Warning = msg, true
+		} else {
+			d.Message = strings.TrimPrefix(d.Message, "error: ")
+		}
+		result = append(result, d)
+	}
+	return result
+}
+
+// CIReporter prints output of each finished module as a collapsible section of CI log.
+// Diagnostics found in output of failed modules are reported as annotations, see `ParseDiagnostics`.
+type CIReporter struct {
+	Provider CIProvider
+
+	// Out defaults to stdout.
+	Out io.Writer
+
+	// Verbose enables printing of commands & output for successful modules, otherwise only a line is printed.
+	Verbose bool
+
+	// Usage enables printing of resource usage in section headers.
+	Usage bool
+
+	lock   sync.Mutex
+	starts map[string]*StartEvent
+}
+
+func NewCIReporter(provider CIProvider) *CIReporter {
+	return &CIReporter{Provider: provider, Out: os.Stdout, starts: make(map[string]*StartEvent)}
+}
+
+func (r *CIReporter) Report(module string, event Event) {
+	DefaultTerminal.Print(func() { r.print(module, event) })
+}
+
+func (r *CIReporter) print(module string, event Event) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	switch e := event.(type) {
+	case *StartEvent:
+		r.starts[module] = e
+
+	case *WarningEvent:
+		switch r.Provider {
+		case CIGitHub:
+			fmt.Fprintf(r.Out, "::warning title=%s::%s\n", githubProperty(module), githubData(e.Message))
+		case CITeamCity:
+			fmt.Fprintf(r.Out, "##teamcity[message text='%s' status='WARNING']\n", teamcityValue(module+": "+e.Message))
+		default:
+			fmt.Fprintf(r.Out, "⚠ %s %s\n", module, e.Message)
+		}
+
+	case *FinishEvent:
+		r.finish(module, e)
+	}
+}
+
+func (r *CIReporter) finish(module string, e *FinishEvent) {
+	duration := e.Duration.Round(time.Millisecond).String()
+	if r.Usage {
+		duration += " " + e.Usage.String()
+	}
+	var header string
+	switch e.Status {
+	case StatusSkipped:
+		fmt.Fprintf(r.Out, "↷ %s skipped: %s\n", module, e.Reason)
+		return
+	case StatusFailed:
+		header = fmt.Sprintf("❌ %s %s", module, duration)
+	default:
+		header = fmt.Sprintf("✓ %s %s", module, duration)
+		if !r.Verbose {
+			fmt.Fprintln(r.Out, header)
+			return
+		}
+	}
+
+	r.open(module, header, e.Status == StatusFailed)
+	for _, s := range e.Steps {
+		if len(e.Steps) > 1 {
+			fmt.Fprintf(r.Out, "# %s %s\n", s.Label, s.Duration.Round(time.Millisecond))
+		}
+		fmt.Fprintf(r.Out, "$ %s\n", s.Command)
+		if len(s.Output) > 0 {
+			fmt.Fprintln(r.Out, strings.TrimRight(string(s.Output), "\n"))
+		}
+	}
+	if e.Err != nil {
+		fmt.Fprintln(r.Out, e.Err)
+	}
+	r.close(module)
+
+	if e.Status == StatusFailed {
+		r.annotate(module, e)
+	}
+}
+
+func (r *CIReporter) open(module, header string, failed bool) {
+	switch r.Provider {
+	case CIGitHub:
+		fmt.Fprintf(r.Out, "::group::%s\n", header)
+	case CIGitLab:
+		fmt.Fprintf(
+			r.Out, "\x1b[0Ksection_start:%d:%s[collapsed=%t]\r\x1b[0K%s\n",
+			time.Now().Unix(), gitlabSection(module), !failed, header,
+		)
+	case CITeamCity:
+		fmt.Fprintf(r.Out, "##teamcity[blockOpened name='%s' description='%s']\n", teamcityValue(module), teamcityValue(header))
+	default:
+		fmt.Fprintln(r.Out, header)
+	}
+}
+
+func (r *CIReporter) close(module string) {
+	switch r.Provider {
+	case CIGitHub:
+		fmt.Fprintln(r.Out, "::endgroup::")
+	case CIGitLab:
+		fmt.Fprintf(r.Out, "\x1b[0Ksection_end:%d:%s\r\x1b[0K\n", time.Now().Unix(), gitlabSection(module))
+	case CITeamCity:
+		fmt.Fprintf(r.Out, "##teamcity[blockClosed name='%s']\n", teamcityValue(module))
+	}
+}
+
+func (r *CIReporter) annotate(module string, e *FinishEvent) {
+	var diagnostics []Diagnostic
+	for _, s := range e.Steps {
+		diagnostics = append(diagnostics, ParseDiagnostics(s.Output)...)
+	}
+	if len(diagnostics) > maxAnnotations {
+		diagnostics = diagnostics[:maxAnnotations]
+	}
+
+	for _, d := range diagnostics {
+		file := r.relative(module, d.File)
+		switch r.Provider {
+		case CIGitHub:
+			level := "error"
+			if d.Warning {
+				level = "warning"
+			}
+			properties := fmt.Sprintf("file=%s,line=%d", githubProperty(file), d.Line)
+			if d.Column > 0 {
+				properties += fmt.Sprintf(",col=%d", d.Column)
+			}
+			fmt.Fprintf(r.Out, "::%s %s,title=%s::%s\n", level, properties, githubProperty(module), githubData(d.Message))
+		case CITeamCity:
+			status := "ERROR"
+			if d.Warning {
+				status = "WARNING"
+			}
+			fmt.Fprintf(r.Out, "##teamcity[message text='%s' status='%s']\n", teamcityValue(fmt.Sprintf("%s:%d: %s", file, d.Line, d.Message)), status)
+		}
+	}
+
+	switch r.Provider {
+	case CIGitHub:
+		fmt.Fprintf(r.Out, "::error title=%s::%s\n", githubProperty(module), githubData(fmt.Sprintf("%s failed: %s", module, e.Err)))
+	case CITeamCity:
+		fmt.Fprintf(r.Out, "##teamcity[buildProblem description='%s']\n", teamcityValue(fmt.Sprintf("%s failed: %s", module, e.Err)))
+	}
+}
+
+// relative returns path of the file relative to CI workspace (or current directory), resolving it against module dir.
+// Files within a copy of the dir are resolved against the original one.
+func (r *CIReporter) relative(module, file string) string {
+	dir, source := "", ""
+	if e := r.starts[module]; e != nil {
+		dir, source = e.Dir, e.SourceDir
+	}
+	if source != "" {
+		if rel, err := filepath.Rel(dir, file); filepath.IsAbs(file) && err == nil && !strings.HasPrefix(rel, "..") {
+			file = rel
+		}
+		dir = source
+	}
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(dir, file)
+	}
+
+	var root string
+	switch r.Provider {
+	case CIGitHub:
+		root = os.Getenv("GITHUB_WORKSPACE")
+	case CIGitLab:
+		root = os.Getenv("CI_PROJECT_DIR")
+	}
+	if root == "" {
+		root, _ = os.Getwd()
+	}
+
+	if rel, err := filepath.Rel(root, file); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return file
+}
+
+// githubData escapes message of a workflow command.
+func githubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// githubProperty escapes property value of a workflow command.
+func githubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+func teamcityValue(s string) string {
+	return strings.NewReplacer("|", "||", "'", "|'", "\n", "|n", "\r", "|r", "[", "|[", "]", "|]").Replace(s)
+}
+
+func gitlabSection(module string) string {
+	return sectionNameUnsafe.ReplaceAllString(module, "_")
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/roboslone/go-framework/v2"
+	"github.com/roboslone/go-framework/v2/fexec"
+)
+
+// affectedFlag is set by either `-affected` (uncommitted changes only) or `-affected=<base-ref>`.
+// Being a boolean flag, it doesn't take base ref as a separate argument.
+type affectedFlag struct {
+	enabled bool
+	base    string
+}
+
+func (f *affectedFlag) String() string {
+	switch {
+	case f == nil:
+		return ""
+	case !f.enabled:
+		return "false"
+	case f.base == "":
+		return "true"
+	default:
+		return f.base
+	}
+}
+
+func (f *affectedFlag) Set(s string) error {
+	if enabled, err := strconv.ParseBool(s); err == nil {
+		f.enabled, f.base = enabled, ""
+		return nil
+	}
+	f.enabled, f.base = true, s
+	return nil
+}
+
+func (f *affectedFlag) IsBoolFlag() bool {
+	return true
+}
+
+// selectAffected returns requested modules, that have an affected module among their dependencies (or are affected
+// themselves), along with all affected modules, that are meant to restrict the run via `framework.Selection`.
+// See `fexec.Affected`.
+func selectAffected(
+	ctx context.Context,
+	cfg *fexec.CommandConfig,
+	modules framework.Modules,
+	dir, base string,
+	names []string,
+) (targets, affected []string, err error) {
+	files, err := fexec.ChangedFiles(ctx, dir, base)
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing changed files: %w", err)
+	}
+
+	// affected modules are found among all dependencies, selection is applied to the run
+	topology, err := framework.NewApplication[any]("fexec", modules).BuildTopology(ctx, names...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("building topology: %w", err)
+	}
+
+	if affected, err = fexec.Affected[any](cfg, modules, topology, files); err != nil {
+		return nil, nil, err
+	}
+	for _, name := range names {
+		if slices.Contains(affected, name) {
+			targets = append(targets, name)
+		}
+	}
+
+	if base == "" {
+		base = "HEAD"
+	}
+	summary := "nothing"
+	if len(affected) > 0 {
+		summary = strings.Join(affected, ", ")
+	}
+	fmt.Println(color.BlackString("affected by %d changed files since %s: %s", len(files), base, summary))
+	return targets, affected, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	framework "github.com/roboslone/go-framework/v2"
+	"github.com/roboslone/go-framework/v2/fexec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectAffected(t *testing.T) {
+	dir := t.TempDir()
+	for path, content := range map[string]string{
+		".fexec.yaml": `
+commands:
+  lib: {script: "true", dir: lib}
+  a: {script: "true", dir: svc/a, dependencies: [lib]}
+  b: {script: "true", dir: svc/b}
+  ci: {dependencies: [a, b]}
+  other: {script: "true", dir: other}
+`,
+		"lib/lib.go":    "package lib\n",
+		"svc/a/main.go": "package main\n",
+		"svc/b/main.go": "package main\n",
+		"other/README":  "other\n",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, path), []byte(content), 0o644))
+	}
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	git("init", "--quiet")
+	git("add", ".")
+	git("commit", "--quiet", "-m", "initial")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "svc/a/main.go"), []byte("package main // changed\n"), 0o644))
+
+	cfg, err := fexec.ParseConfig(filepath.Join(dir, ".fexec.yaml"))
+	require.NoError(t, err)
+	modules := fexec.BuildModules[any](cfg, dir, nil, nil)
+
+	targets, affected, err := selectAffected(t.Context(), cfg, modules, dir, "", []string{"ci", "other"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"ci"}, targets, "aggregates with affected dependencies are kept")
+	require.Equal(t, []string{"a", "ci"}, affected)
+
+	// unaffected dependencies of targets don't run
+	app := framework.NewApplication("fexec", modules, framework.WithSelection[any](framework.Selection{Modules: affected}))
+	topology, err := app.BuildTopology(t.Context(), targets...)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "ci"}, slices.Sorted(slices.Values(topology.OrderedModuleNames)))
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/roboslone/go-framework/v2/fexec"
+)
+
+// Subcommands are matched on the first argument and take precedence over modules of the same name.
+var Subcommands = []string{"list", "describe", "validate", "schema", "stats", "init", "import", "completion"}
+
+// shadowedCommands returns names of commands that can't be run, as subcommands of the same name take precedence.
+func shadowedCommands(cfg *fexec.CommandConfig) []string {
+	var names []string
+	for _, name := range Subcommands {
+		if _, ok := cfg.Commands[name]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+var completionScripts = map[string]string{
+	"bash": `_fexec() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local words="$(fexec list 2>/dev/null)"
+    if [ "${COMP_CWORD}" -eq 1 ]; then
+        words="${words} %[1]s"
+    fi
+    COMPREPLY=($(compgen -W "${words}" -- "${cur}"))
+    # module names may contain colons, which bash treats as word breaks
+    if declare -F __ltrim_colon_completions >/dev/null; then
+        __ltrim_colon_completions "${cur}"
+    fi
+}
+complete -F _fexec fexec
+`,
+	"zsh": `#compdef fexec
+_fexec() {
+    local -a words
+    words=(${(f)"$(fexec list 2>/dev/null)"})
+    if (( CURRENT == 2 )); then
+        words+=(%[1]s)
+    fi
+    compadd -a words
+}
+compdef _fexec fexec
+`,
+	"fish": `complete -c fexec -f -a '(fexec list 2>/dev/null)'
+complete -c fexec -f -n '__fish_use_subcommand' -a '%[1]s'
+`,
+}
+
+// Completion prints completion script for given shell. Scripts complete module names using `fexec list`.
+func Completion(w io.Writer, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: fexec completion %s", strings.Join(slices.Sorted(maps.Keys(completionScripts)), "|"))
+	}
+
+	script, ok := completionScripts[args[0]]
+	if !ok {
+		return fmt.Errorf("unsupported shell: %q", args[0])
+	}
+
+	_, err := fmt.Fprintf(w, script, strings.Join(Subcommands, " "))
+	return err
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/roboslone/go-framework/v2/fexec"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+func TestCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		t.Run(shell, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			require.NoError(t, Completion(out, []string{shell}))
+
+			path := filepath.Join("testdata", "completion."+shell)
+			if *update {
+				require.NoError(t, os.WriteFile(path, out.Bytes(), 0o644))
+			}
+			expected, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.Equal(t, string(expected), out.String())
+
+			// the script is at least parsed by the shell, if it's installed
+			if _, err = exec.LookPath(shell); err != nil {
+				t.Skipf("%s is not installed", shell)
+			}
+			output, err := exec.Command(shell, "-n", path).CombinedOutput()
+			require.NoError(t, err, string(output))
+		})
+	}
+
+	require.ErrorContains(t, Completion(&bytes.Buffer{}, []string{"tcsh"}), `unsupported shell: "tcsh"`)
+	require.ErrorContains(t, Completion(&bytes.Buffer{}, nil), "usage: fexec completion bash|fish|zsh")
+}
+
+func TestShadowedCommands(t *testing.T) {
+	cfg := &fexec.CommandConfig{Commands: map[string]fexec.Command{
+		"build": {},
+		"list":  {},
+		"stats": {},
+	}}
+	require.Equal(t, []string{"list", "stats"}, shadowedCommands(cfg))
+
+	delete(cfg.Commands, "list")
+	delete(cfg.Commands, "stats")
+	require.Empty(t, shadowedCommands(cfg))
+}
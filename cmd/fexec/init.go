@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/fatih/color"
+	"github.com/roboslone/go-framework/v2/fexec"
+)
+
+// Init writes a starter config generated from project files in the current directory, see `fexec.Scaffold`.
+func Init(args []string) error {
+	fs := flag.NewFlagSet("init", flag.ContinueOnError)
+	output := fs.String("o", ".fexec.yaml", "Write config to `path`, `-` for stdout")
+	force := fs.Bool("f", false, "Overwrite existing config")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := fexec.Scaffold(".")
+	if err != nil {
+		return err
+	}
+	return writeConfig(cfg, *output, *force)
+}
+
+// Import converts configs of other tools into fexec config, only makefiles are supported.
+func Import(args []string) error {
+	if len(args) == 0 || args[0] != "make" {
+		return errors.New("usage: fexec import make [-o path] [-f] [makefile]")
+	}
+
+	fs := flag.NewFlagSet("import make", flag.ContinueOnError)
+	output := fs.String("o", "-", "Write config to `path`, `-` for stdout")
+	force := fs.Bool("f", false, "Overwrite existing config")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	path := fs.Arg(0)
+	if path == "" {
+		var err error
+		if path, err = fexec.FindMakefile("."); err != nil {
+			return err
+		}
+	}
+
+	cfg, skipped, err := fexec.ImportMakefile(path)
+	if err != nil {
+		return err
+	}
+	for _, s := range skipped {
+		fmt.Fprintln(os.Stderr, color.YellowString("⚠ %s: %s", path, s))
+	}
+	if len(cfg.Commands) == 0 {
+		return fmt.Errorf("no targets to import from %s", path)
+	}
+	return writeConfig(cfg, *output, *force)
+}
+
+func writeConfig(cfg *fexec.CommandConfig, path string, force bool) error {
+	content, err := fexec.EncodeConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	if path == "-" {
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists, use -f to overwrite it", path)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(content); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s %s %s\n", color.GreenString("✓"), path, color.BlackString("%d commands: %v", len(cfg.Commands), slices.Sorted(maps.Keys(cfg.Commands))))
+	return nil
+}
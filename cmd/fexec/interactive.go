@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/roboslone/go-framework/v2/fexec"
+)
+
+// lastRunFileName is a name of file within `fexec.HistoryDir`, that holds arguments of the last picked run.
+// Unlike history, it's written regardless of `-history`, so that `!!` works right after `-i`.
+const lastRunFileName = "last-run.json"
+
+// lastRun is a run started from the picker, see `saveLastRun`.
+type lastRun struct {
+	Started time.Time `json:"started"`
+	Args    []string  `json:"args"`
+}
+
+// invocation returns command line arguments reproducing the run: flags set explicitly
+// (except for `-c` and `-i`), followed by positional arguments.
+func invocation(fs *flag.FlagSet, positional []string) []string {
+	var args []string
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "c" || f.Name == "i" {
+			return
+		}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() && f.Value.String() == "true" {
+			args = append(args, "-"+f.Name)
+		} else {
+			args = append(args, "-"+f.Name+"="+f.Value.String())
+		}
+	})
+	return append(args, positional...)
+}
+
+// saveLastRun records arguments of a run started from the picker in `dir`, see `lastInvocation`.
+func saveLastRun(dir string, args []string) error {
+	content, err := json.Marshal(lastRun{Started: time.Now(), Args: args})
+	if err != nil {
+		return fmt.Errorf("encoding last run: %w", err)
+	}
+
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating %s: %w", dir, err)
+	}
+	// local state, keep it out of version control
+	if err = os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("*\n"), 0o644); err != nil {
+		return fmt.Errorf("writing .gitignore: %w", err)
+	}
+	return os.WriteFile(filepath.Join(dir, lastRunFileName), content, 0o644)
+}
+
+// lastInvocation returns arguments of the most recent run, either picked (see `saveLastRun`)
+// or recorded in history located in `dir`, see `invocation`.
+func lastInvocation(dir string) ([]string, error) {
+	last := lastRun{}
+	content, err := os.ReadFile(filepath.Join(dir, lastRunFileName))
+	if err == nil {
+		if err = json.Unmarshal(content, &last); err != nil {
+			return nil, fmt.Errorf("decoding last run: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading last run: %w", err)
+	}
+
+	runs, err := fexec.ReadHistory(filepath.Join(dir, fexec.HistoryFileName))
+	if err != nil {
+		return nil, err
+	}
+	for i := len(runs) - 1; i >= 0; i-- {
+		if len(runs[i].Args) > 0 {
+			if runs[i].Started.After(last.Started) {
+				return runs[i].Args, nil
+			}
+			break
+		}
+	}
+
+	if len(last.Args) == 0 {
+		return nil, fmt.Errorf("no runs recorded in %s, pick a module with -i or enable history with -history", dir)
+	}
+	return last.Args, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/roboslone/go-framework/v2/fexec"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestFlagSet returns a subset of fexec flags, covering every kind of flag value.
+func newTestFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("fexec", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.String("c", "", "")
+	fs.Bool("i", false, "")
+	fs.Bool("v", false, "")
+	fs.Bool("only", false, "")
+	fs.String("from", "", "")
+	fs.Var(&affectedFlag{}, "affected", "")
+	fs.Var(&notifyFlag{}, "notify", "")
+	return fs
+}
+
+func TestInvocation(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{name: "positional", args: []string{"build", "test"}, expected: []string{"build", "test"}},
+		{name: "config and interactive are dropped", args: []string{"-c", "other.yaml", "-i", "-v", "build"}, expected: []string{"-v", "build"}},
+		{name: "bool", args: []string{"-only", "-v=false", "build"}, expected: []string{"-only", "-v=false", "build"}},
+		{name: "string", args: []string{"-from", "a,b", "all"}, expected: []string{"-from=a,b", "all"}},
+		{name: "overrides", args: []string{"build", "build.GOOS=linux"}, expected: []string{"build", "build.GOOS=linux"}},
+		{name: "affected", args: []string{"-affected", "build"}, expected: []string{"-affected", "build"}},
+		{name: "affected base", args: []string{"-affected=origin/main", "build"}, expected: []string{"-affected=origin/main", "build"}},
+		{name: "affected disabled", args: []string{"-affected=false", "build"}, expected: []string{"-affected=false", "build"}},
+		{name: "notify", args: []string{"-notify", "build"}, expected: []string{"-notify", "build"}},
+		{name: "notify after", args: []string{"-notify=90s", "build"}, expected: []string{"-notify=1m30s", "build"}},
+		{name: "notify disabled", args: []string{"-notify=false", "build"}, expected: []string{"-notify=false", "build"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := newTestFlagSet()
+			require.NoError(t, fs.Parse(tc.args))
+
+			args := invocation(fs, fs.Args())
+			require.Equal(t, tc.expected, args)
+
+			// arguments reproduce the same flags
+			replayed := newTestFlagSet()
+			require.NoError(t, replayed.Parse(args))
+			require.Equal(t, args, invocation(replayed, replayed.Args()))
+			fs.Visit(func(f *flag.Flag) {
+				if f.Name != "c" && f.Name != "i" {
+					require.Equal(t, f.Value.String(), replayed.Lookup(f.Name).Value.String(), f.Name)
+				}
+			})
+		})
+	}
+}
+
+func TestLastInvocation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, fexec.HistoryFileName)
+
+	_, err := lastInvocation(dir)
+	require.ErrorContains(t, err, "no runs recorded")
+
+	// picked runs are recorded without history
+	require.NoError(t, saveLastRun(dir, []string{"-v", "lint"}))
+	require.FileExists(t, filepath.Join(dir, ".gitignore"))
+	require.NoFileExists(t, path)
+
+	args, err := lastInvocation(dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{"-v", "lint"}, args)
+
+	record := func(args []string) {
+		recorder := fexec.NewHistoryRecorder(t.Context(), dir, []string{"build"})
+		recorder.SetArgs(args)
+		require.NoError(t, recorder.Save(path, nil))
+	}
+
+	record([]string{"-affected=origin/main", "build"})
+	record([]string{"-notify", "-v", "test"})
+
+	args, err = lastInvocation(dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{"-notify", "-v", "test"}, args)
+
+	// runs recorded without arguments are skipped
+	record(nil)
+
+	args, err = lastInvocation(dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{"-notify", "-v", "test"}, args)
+
+	// the most recent run wins
+	require.NoError(t, saveLastRun(dir, []string{"deploy"}))
+
+	args, err = lastInvocation(dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{"deploy"}, args)
+}
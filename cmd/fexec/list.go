@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/roboslone/go-framework/v2"
+	"github.com/roboslone/go-framework/v2/fexec"
+)
+
+// ListEntry describes a command as written in config, see `List`.
+type ListEntry struct {
+	Name         string                  `json:"name"`
+	Dir          string                  `json:"dir,omitempty"`
+	Steps        []framework.CommandStep `json:"steps,omitempty"`
+	Dependencies []string                `json:"dependencies,omitempty"`
+}
+
+// Description describes a command with variables substituted, along with its place in the dependency graph.
+type Description struct {
+	Name            string                  `json:"name"`
+	Dir             string                  `json:"dir,omitempty"`
+	Env             []string                `json:"env,omitempty"`
+	Steps           []framework.CommandStep `json:"steps,omitempty"`
+	Dependencies    []string                `json:"dependencies"`
+	AllDependencies []string                `json:"all_dependencies"`
+	Dependents      []string                `json:"dependents"`
+	AllDependents   []string                `json:"all_dependents"`
+}
+
+// List prints names of all commands, one per line, or all commands as JSON array with `--json`.
+func List(cfg *fexec.CommandConfig, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "Print commands as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	names := slices.Sorted(maps.Keys(cfg.Commands))
+	if !*asJSON {
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return nil
+	}
+
+	return printJSON(listEntries(cfg))
+}
+
+// listEntries describes all commands of the config, sorted by name.
+func listEntries(cfg *fexec.CommandConfig) []ListEntry {
+	entries := make([]ListEntry, 0, len(cfg.Commands))
+	for _, name := range slices.Sorted(maps.Keys(cfg.Commands)) {
+		c := cfg.Commands[name]
+		entry := ListEntry{Name: name, Dir: c.Dir, Dependencies: c.DependsOn}
+		if !c.IsNoop() {
+			entry.Steps = c.Steps
+			if len(entry.Steps) == 0 {
+				entry.Steps = []framework.CommandStep{{Command: c.Command, Script: c.Script}}
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// Describe prints fully resolved command, along with its dependencies and dependents.
+// Arguments are a module name and optional `KEY=VALUE` variable overrides.
+func Describe(ctx context.Context, cfg *fexec.CommandConfig, wd string, args []string) error {
+	fs := flag.NewFlagSet("describe", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "Print description as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	names, overrides := fexec.ParseOverrides(fs.Args())
+	if len(names) != 1 {
+		return fmt.Errorf("expected a single module name, got %d", len(names))
+	}
+	name := names[0]
+	if _, ok := cfg.Commands[name]; !ok {
+		return fmt.Errorf("module not found: %q", name)
+	}
+
+	vars, err := fexec.ResolveVars(ctx, cfg.Vars, overrides)
+	if err != nil {
+		return fmt.Errorf("resolving variables: %w", err)
+	}
+
+	modules := fexec.BuildModules[any](cfg, wd, vars, overrides)
+	app := framework.NewApplication[any]("fexec", modules)
+	topology, err := app.BuildTopology(ctx, slices.Sorted(maps.Keys(modules))...)
+	if err != nil {
+		return fmt.Errorf("building topology: %w", err)
+	}
+
+	d := Description{
+		Name:            name,
+		Dependencies:    nonNil(topology.DirectDependencies[name]),
+		AllDependencies: nonNil(topology.FullDependencies[name]),
+		Dependents:      []string{},
+		AllDependents:   []string{},
+	}
+	for _, other := range topology.OrderedModuleNames {
+		if slices.Contains(topology.DirectDependencies[other], name) {
+			d.Dependents = append(d.Dependents, other)
+		}
+		if slices.Contains(topology.FullDependencies[other], name) {
+			d.AllDependents = append(d.AllDependents, other)
+		}
+	}
+
+	if cm, ok := modules[name].(*framework.CommandModule[any]); ok {
+		resolved, err := cm.Resolve(ctx)
+		if err != nil {
+			return err
+		}
+		d.Dir, d.Env, d.Steps = resolved.Dir, resolved.Env, resolved.Steps
+	}
+
+	if *asJSON {
+		return printJSON(d)
+	}
+	d.Print()
+	return nil
+}
+
+func (d *Description) Print() {
+	result := strings.Builder{}
+
+	result.WriteString(d.Name + "\n")
+	for i, step := range d.Steps {
+		if len(d.Steps) > 1 {
+			result.WriteString(color.BlackString("\t%d. %s\n", i+1, step.Name))
+		}
+		result.WriteString(color.BlackString("\t$ %s\n", indent(step.String(), "\t")))
+	}
+	if d.Dir != "" {
+		result.WriteString(color.BlackString("\t@%s\n", d.Dir))
+	}
+	for _, kv := range d.Env {
+		result.WriteString(color.BlackString("\t%s\n", kv))
+	}
+
+	for _, line := range []struct {
+		title string
+		names []string
+	}{
+		{"depends on", d.Dependencies},
+		{"depends on (transitively)", d.AllDependencies},
+		{"required by", d.Dependents},
+		{"required by (transitively)", d.AllDependents},
+	} {
+		if len(line.names) > 0 {
+			result.WriteString(fmt.Sprintf("%s %s\n", line.title, color.BlackString(strings.Join(line.names, ", "))))
+		}
+	}
+
+	fmt.Print(result.String())
+}
+
+func printJSON(v any) error {
+	e := json.NewEncoder(os.Stdout)
+	e.SetIndent("", "  ")
+	return e.Encode(v)
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
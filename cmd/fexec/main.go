@@ -1,68 +1,44 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"log"
-	"maps"
 	"os"
 	"path/filepath"
 	"slices"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
 	"github.com/roboslone/go-framework/v2"
-	"gopkg.in/yaml.v3"
+	"github.com/roboslone/go-framework/v2/fexec"
 )
 
-const (
-	discoverMaxDepth = 7
-)
-
-var (
-	discoverNames = []string{
-		".fexec.yaml",
-		".fexec.yml",
-	}
-)
-
-type CommandConfig struct {
-	Commands map[string]framework.CommandModule[any] `yaml:"commands"`
-}
-
-func (cfg *CommandConfig) PrintUsage(configPath string) {
-	result := strings.Builder{}
-
-	result.WriteString(color.BlackString("\n@%s\n", configPath))
-	result.WriteString("Available modules:\n")
-
-	for _, name := range slices.Sorted(maps.Keys(cfg.Commands)) {
-		result.WriteString(fmt.Sprintf("\t%s\n", name))
-
-		module := cfg.Commands[name]
-		if len(module.Command) > 0 {
-			result.WriteString(color.BlackString("\t\t$ %s\n", strings.Join(module.Command, " ")))
-		}
-		if module.Dir != "" {
-			result.WriteString(color.BlackString("\t\t@%s\n", module.Dir))
-		}
-		if len(module.DependsOn) > 0 {
-			result.WriteString(color.BlackString("\t\tdepends on %s\n", strings.Join(module.DependsOn, ", ")))
-		}
-	}
-
-	fmt.Println(result.String())
-}
-
 func main() {
 	fs := flag.NewFlagSet("fexec", flag.ContinueOnError)
 
 	wd := "."
 	configPath := fs.String("c", "", "Path to config file")
+	interactive := fs.Bool("i", false, "Pick a module to run interactively")
 	verbose := fs.Bool("v", false, "Show command descriptions & output for successful commands")
 	live := fs.Bool("l", false, "Show live output of all commands")
+	usage := fs.Bool("usage", false, "Show CPU time and peak memory of each command")
+	plain := fs.Bool("plain", false, "Disable progress view, print a line per finished command instead")
+	history := fs.Bool("history", false, "Record the run in `.fexec/history.jsonl` next to config (along with git commit and dirty state), see `fexec stats`. Overrides `history` of the config")
+	only := fs.Bool("only", false, "Only run given modules, without their dependencies")
+	depsOnly := fs.Bool("deps-only", false, "Only run dependencies of given modules, without the modules themselves")
+	from := fs.String("from", "", "Only run given `modules` (comma-separated) and modules depending on them")
+	affected := &affectedFlag{}
+	fs.Var(affected, "affected", "Only run given modules affected by changes since `base-ref` (uncommitted changes, if omitted), skipping their unaffected dependencies. The base ref requires `=`")
+	ci := fs.String("ci", "auto", "Fold output of each command in CI log and annotate diagnostics of failed commands: `auto`, github, gitlab, teamcity or none")
+	notify := &notifyFlag{}
+	fs.Var(notify, "notify", "Ring the bell and send a desktop notification once a run longer than `duration` finishes (10s, if omitted), false disables notifications of the config")
+	logDir := fs.String("log-dir", "", "Write full output of each command to `<dir>/<module>.log`, along with summary `index.json`")
 
 	flagErr := fs.Parse(os.Args[1:])
 	printUsage := errors.Is(flagErr, flag.ErrHelp) || len(os.Args) == 1
@@ -70,9 +46,32 @@ func main() {
 		log.Fatalf("parsing options: %s", flagErr)
 	}
 
+	switch fs.Arg(0) {
+	case "completion":
+		if err := Completion(os.Stdout, fs.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "schema":
+		if err := PrintSchema(fs.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "init":
+		if err := Init(fs.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "import":
+		if err := Import(fs.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if *configPath == "" {
 		var err error
-		*configPath, err = DiscoverConfigPath()
+		*configPath, err = fexec.DiscoverConfigPath()
 		if err != nil {
 			log.Fatalf("discovering config path: %s", err)
 		}
@@ -80,12 +79,22 @@ func main() {
 		wd = filepath.Dir(*configPath)
 	}
 
-	cfg, err := ParseConfig(*configPath)
+	if fs.Arg(0) == "validate" {
+		if err := Validate(*configPath); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	cfg, err := fexec.ParseConfig(*configPath)
 	if err != nil {
 		log.Fatalf("reading config: %s", err)
 	}
+	for _, name := range shadowedCommands(cfg) {
+		fmt.Fprintln(os.Stderr, color.YellowString("⚠ command %q is shadowed by subcommand `fexec %s`, rename it to run it", name, name))
+	}
 	if printUsage {
-		cfg.PrintUsage(*configPath)
+		PrintUsage(cfg, *configPath)
 
 		if len(fs.Args()) == 1 {
 			os.Exit(1)
@@ -98,54 +107,203 @@ func main() {
 		log.Fatalf("setting up common env: %s", err)
 	}
 
-	modules := framework.Modules{}
-	for name, module := range cfg.Commands {
-		if len(module.Command) == 0 && module.Dir == "" && len(module.Env) == 0 {
-			modules[name] = &framework.NoopModule{DependsOn: module.DependsOn}
-			continue
+	ctx, cancel := framework.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	configDir := filepath.Dir(*configPath)
+	stateDir := filepath.Join(configDir, fexec.HistoryDir)
+	historyPath := filepath.Join(stateDir, fexec.HistoryFileName)
+
+	if fs.Arg(0) == "!!" {
+		args, err := lastInvocation(stateDir)
+		if err != nil {
+			log.Fatalf("repeating last run: %s", err)
 		}
+		args = append(args, fs.Args()[1:]...)
+		fmt.Println(color.BlackString("$ fexec %s", strings.Join(args, " ")))
+		if err = fs.Parse(args); err != nil {
+			log.Fatalf("parsing options: %s", err)
+		}
+	}
 
-		if module.Dir == "" {
-			module.Dir = wd
-		} else if !filepath.IsAbs(module.Dir) {
-			module.Dir = filepath.Join(wd, module.Dir)
+	switch fs.Arg(0) {
+	case "list":
+		if err = List(cfg, fs.Args()[1:]); err != nil {
+			log.Fatal(err)
 		}
+		return
+	case "describe":
+		if err = Describe(ctx, cfg, wd, fs.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	case "stats":
+		if err = Stats(ctx, cfg, historyPath, fs.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-		m := &framework.CommandModule[any]{
-			Command:       module.Command,
-			Dir:           module.Dir,
-			Env:           module.Env,
-			DependsOn:     module.DependsOn,
-			ErrorOnOutput: module.ErrorOnOutput,
-			Verbose:       module.Verbose,
-			Live:          module.Live,
+	names, overrides := fexec.ParseOverrides(fs.Args())
+	positional := fs.Args()
+	if *interactive {
+		if len(names) > 0 {
+			log.Fatalf("-i doesn't accept module names, got %s", strings.Join(names, ", "))
 		}
 
-		if verbose != nil {
-			m.Verbose = *verbose
+		name, err := Pick(os.Stdin, os.Stdout, listEntries(cfg))
+		if errors.Is(err, errPickCancelled) {
+			os.Exit(130)
+		}
+		if err != nil {
+			log.Fatalf("picking module: %s", err)
 		}
-		if live != nil {
-			m.Live = *live
+
+		names, positional = []string{name}, append([]string{name}, positional...)
+		args := invocation(fs, positional)
+		fmt.Println(color.BlackString("$ fexec %s", strings.Join(args, " ")))
+		if err = saveLastRun(stateDir, args); err != nil {
+			log.Printf("recording last run: %s", err)
 		}
+	}
 
-		modules[name] = m
+	vars, err := fexec.ResolveVars(ctx, cfg.Vars, overrides)
+	if err != nil {
+		log.Fatalf("resolving variables: %s", err)
 	}
 
-	framework.NewApplication[any]("fexec", modules).Main(framework.WithArgs(fs.Args()...))
-}
+	modules := fexec.BuildModules[any](cfg, wd, vars, overrides)
 
-func ParseConfig(path string) (*CommandConfig, error) {
-	content, err := os.ReadFile(path)
+	selection := framework.Selection{Only: *only, DependenciesOnly: *depsOnly}
+	if *from != "" {
+		selection.From = strings.Split(*from, ",")
+		if len(names) == 0 {
+			names = []string{"*"}
+		}
+	}
+
+	app := framework.NewApplication("fexec", modules, framework.WithSelection[any](selection))
+	if names, err = app.Glob(names...); err != nil {
+		if affected.enabled && affected.base == "" {
+			log.Fatalf("%s (base ref must be passed as -affected=<base-ref>)", err)
+		}
+		log.Fatal(err)
+	}
+	if affected.enabled {
+		if names, selection.Modules, err = selectAffected(ctx, cfg, modules, configDir, affected.base, names); err != nil {
+			log.Fatalf("selecting affected modules: %s", err)
+		}
+		if len(names) == 0 {
+			return
+		}
+		app = framework.NewApplication("fexec", modules, framework.WithSelection[any](selection))
+	}
+
+	var shared framework.Reporters
+
+	provider, err := ciProvider(*ci)
 	if err != nil {
-		return nil, fmt.Errorf("read: %w", err)
+		log.Fatal(err)
 	}
 
-	cfg := &CommandConfig{}
-	if err = yaml.Unmarshal(content, cfg); err != nil {
-		return nil, fmt.Errorf("parse: %w", err)
+	var progress *ProgressView
+	if !*plain && !*live && isatty.IsTerminal(os.Stdout.Fd()) {
+		topology, err := app.BuildTopology(ctx, names...)
+		if err != nil {
+			log.Fatalf("building topology: %s", err)
+		}
+
+		progress = NewProgressView(os.Stdout, topology, modules, *verbose, *usage)
+		shared = append(shared, progress)
+	}
+
+	var logs *framework.LogReporter
+	if *logDir != "" {
+		if logs, err = framework.NewLogReporter(*logDir); err != nil {
+			log.Fatal(err)
+		}
+		shared = append(shared, logs)
+	}
+
+	record := cfg.History
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "history" {
+			record = *history
+		}
+	})
+
+	var recorder *fexec.HistoryRecorder
+	if record {
+		recorder = fexec.NewHistoryRecorder(ctx, configDir, names)
+		recorder.SetArgs(invocation(fs, positional))
+		shared = append(shared, recorder)
+	}
+
+	var notifier *fexec.Notifier
+	if notifyConfig := notify.apply(cfg.Notify); notifyConfig.Enabled() {
+		notifier = fexec.NewNotifier(notifyConfig, configDir, cfg.Shell, names)
+		shared = append(shared, notifier)
+	}
+
+	for _, m := range modules {
+		if cm, ok := m.(*framework.CommandModule[any]); ok {
+			cm.Verbose = cm.Verbose || *verbose
+			cm.Live = cm.Live || *live
+
+			reporters := slices.Clone(shared)
+			switch {
+			case progress != nil:
+			case provider != "" && !cm.Live:
+				ciReporter := framework.NewCIReporter(provider)
+				ciReporter.Verbose, ciReporter.Usage = cm.Verbose, *usage
+				reporters = append(reporters, ciReporter)
+			default:
+				reporters = append(reporters, &framework.ConsoleReporter{Verbose: cm.Verbose, Live: cm.Live, Usage: *usage})
+			}
+			cm.Reporter = reporters
+		}
 	}
 
-	return cfg, nil
+	if progress != nil {
+		progress.Start()
+	}
+	err = app.Run(ctx, context.Background(), new(any), names...)
+	if progress != nil {
+		progress.Stop()
+	}
+	if logs != nil {
+		if logErr := logs.Close(); logErr != nil {
+			log.Printf("writing logs: %s", logErr)
+		}
+	}
+	if recorder != nil {
+		if historyErr := recorder.Save(historyPath, err); historyErr != nil {
+			log.Printf("recording history: %s", historyErr)
+		}
+	}
+	if notifier != nil {
+		if notifyErr := notifier.Notify(ctx, err); notifyErr != nil {
+			log.Printf("notifying: %s", notifyErr)
+		}
+	}
+	if err != nil {
+		cancel()
+		log.Fatal(err)
+	}
+}
+
+// ciProvider parses value of `-ci` flag, `auto` detects provider from environment.
+func ciProvider(value string) (framework.CIProvider, error) {
+	switch p := framework.CIProvider(value); p {
+	case "auto":
+		return framework.DetectCI(), nil
+	case "none", "":
+		return "", nil
+	case framework.CIGitHub, framework.CIGitLab, framework.CITeamCity:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown CI provider: %q", value)
+	}
 }
 
 func SetupCommonEnv() error {
@@ -158,26 +316,3 @@ func SetupCommonEnv() error {
 	}
 	return nil
 }
-
-func DiscoverConfigPath() (string, error) {
-	wd, err := os.Getwd()
-	if err != nil {
-		return "", fmt.Errorf("getwd: %w", err)
-	}
-
-	for depth := 0; depth < discoverMaxDepth; depth++ {
-		for _, name := range discoverNames {
-			path := fmt.Sprintf("%s/%s", wd, name)
-
-			if _, err = os.Stat(path); err == nil {
-				return path, nil
-			}
-			if !errors.Is(err, os.ErrNotExist) {
-				return "", fmt.Errorf("stat %q: %w", path, err)
-			}
-		}
-		wd = filepath.Dir(wd)
-	}
-
-	return "", fmt.Errorf("file not found (searched for %s)", strings.Join(discoverNames, ", "))
-}
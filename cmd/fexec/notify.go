@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/roboslone/go-framework/v2/fexec"
+)
+
+// notifyFlag is set by either `-notify` (default threshold), `-notify=<duration>` or `-notify=false`.
+type notifyFlag struct {
+	set     bool
+	enabled bool
+	after   time.Duration
+}
+
+func (f *notifyFlag) String() string {
+	switch {
+	case f == nil || !f.set:
+		return ""
+	case !f.enabled:
+		return "false"
+	case f.after == 0:
+		return "true"
+	default:
+		return f.after.String()
+	}
+}
+
+func (f *notifyFlag) Set(s string) error {
+	if enabled, err := strconv.ParseBool(s); err == nil {
+		f.set, f.enabled, f.after = true, enabled, 0
+		return nil
+	}
+	after, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("expected boolean or duration, got %q", s)
+	}
+	if after < 0 {
+		return fmt.Errorf("duration must not be negative, got %s", s)
+	}
+	f.set, f.enabled, f.after = true, true, after
+	return nil
+}
+
+func (f *notifyFlag) IsBoolFlag() bool {
+	return true
+}
+
+// apply overrides notifications of the config: `-notify` enables terminal notifications,
+// `-notify=false` disables all of them.
+func (f *notifyFlag) apply(config fexec.NotifyConfig) fexec.NotifyConfig {
+	switch {
+	case !f.set:
+		return config
+	case !f.enabled:
+		return fexec.NotifyConfig{}
+	}
+
+	config.Terminal = true
+	if f.after > 0 {
+		config.After = f.after
+	}
+	return config
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"golang.org/x/term"
+)
+
+const pickerMaxHeight = 15
+
+var errPickCancelled = errors.New("cancelled")
+
+// picker is a fuzzy-searchable list of modules, see `Pick`.
+type picker struct {
+	out     *os.File
+	entries []ListEntry
+
+	query   []rune
+	matches []int
+	cursor  int
+	offset  int
+}
+
+// Pick shows modules on the terminal, filtered by typed query, and returns name of the selected one.
+// Arrows (or Ctrl+P/Ctrl+N) move the selection, Enter runs selected module, Esc or Ctrl+C cancel.
+func Pick(in, out *os.File, entries []ListEntry) (string, error) {
+	if !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return "", errors.New("interactive mode requires a terminal")
+	}
+
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return "", fmt.Errorf("switching terminal to raw mode: %w", err)
+	}
+	defer term.Restore(int(in.Fd()), state)
+
+	p := &picker{out: out, entries: entries}
+	p.filter()
+
+	buf := make([]byte, 256)
+	for {
+		p.render()
+
+		n, err := in.Read(buf)
+		if err != nil {
+			p.clear()
+			return "", err
+		}
+
+		selected, err := p.handle(buf[:n])
+		if selected != "" || err != nil {
+			p.clear()
+			return selected, err
+		}
+	}
+}
+
+// handle applies keys read from terminal, returning selected module once Enter is pressed.
+func (p *picker) handle(input []byte) (string, error) {
+	for len(input) > 0 {
+		key := input[0]
+		input = input[1:]
+
+		switch key {
+		case 3, 4: // Ctrl+C, Ctrl+D
+			return "", errPickCancelled
+		case '\r', '\n':
+			if len(p.matches) > 0 {
+				return p.entries[p.matches[p.cursor]].Name, nil
+			}
+		case 127, 8: // Backspace
+			if len(p.query) > 0 {
+				p.query = p.query[:len(p.query)-1]
+				p.filter()
+			}
+		case 21: // Ctrl+U
+			p.query = nil
+			p.filter()
+		case 16: // Ctrl+P
+			p.move(-1)
+		case 14, '\t': // Ctrl+N
+			p.move(1)
+		case 27:
+			if len(input) == 0 {
+				return "", errPickCancelled
+			}
+			// CSI (`ESC [`) or SS3 (`ESC O`) sequence, e.g. arrows
+			seq := input[1:]
+			end := slices.IndexFunc(seq, func(b byte) bool { return b >= 0x40 && b <= 0x7e })
+			if end < 0 {
+				return "", nil
+			}
+			switch string(seq[:end+1]) {
+			case "A":
+				p.move(-1)
+			case "B":
+				p.move(1)
+			case "5~":
+				p.move(-pickerMaxHeight)
+			case "6~":
+				p.move(pickerMaxHeight)
+			}
+			input = seq[end+1:]
+		default:
+			if key < 0x20 {
+				continue
+			}
+			r, size := utf8.DecodeRune(append([]byte{key}, input...))
+			input = input[size-1:]
+			if unicode.IsPrint(r) {
+				p.query = append(p.query, r)
+				p.filter()
+			}
+		}
+	}
+	return "", nil
+}
+
+func (p *picker) move(delta int) {
+	if len(p.matches) == 0 {
+		return
+	}
+	p.cursor = min(max(p.cursor+delta, 0), len(p.matches)-1)
+}
+
+// filter selects entries matching the query, best matches first.
+func (p *picker) filter() {
+	type match struct {
+		index, score int
+	}
+
+	var matches []match
+	for i, e := range p.entries {
+		score, ok := fuzzyScore(p.query, e.Name)
+		if !ok {
+			// commands are searched too, but ranked below names
+			if score, ok = fuzzyScore(p.query, summary(e)); ok {
+				score -= 1 << 20
+			}
+		}
+		if ok {
+			matches = append(matches, match{i, score})
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b match) int { return b.score - a.score })
+
+	p.matches = p.matches[:0]
+	for _, m := range matches {
+		p.matches = append(p.matches, m.index)
+	}
+	p.cursor, p.offset = 0, 0
+}
+
+// fuzzyScore matches characters of the query in order within the candidate, ignoring case.
+// Consecutive matches and matches at word boundaries score higher, shorter candidates win ties.
+func fuzzyScore(query []rune, candidate string) (int, bool) {
+	if len(query) == 0 {
+		return 0, true
+	}
+
+	runes := []rune(strings.ToLower(candidate))
+	score, matched, previous := 0, 0, -2
+	for i, r := range runes {
+		if matched == len(query) {
+			break
+		}
+		if r != unicode.ToLower(query[matched]) {
+			continue
+		}
+
+		switch {
+		case i == previous+1:
+			score += 3
+		case i == 0 || strings.ContainsRune(" :-_/.[", runes[i-1]):
+			score += 2
+		default:
+			score++
+		}
+		previous = i
+		matched++
+	}
+	if matched < len(query) {
+		return 0, false
+	}
+	return score<<10 - len(runes), true
+}
+
+// summary returns the first line of the first step, or dependencies of noop modules.
+func summary(e ListEntry) string {
+	if len(e.Steps) == 0 {
+		return "→ " + strings.Join(e.Dependencies, ", ")
+	}
+	line, _, _ := strings.Cut(e.Steps[0].String(), "\n")
+	if len(e.Steps) > 1 {
+		line += fmt.Sprintf(" (+%d steps)", len(e.Steps)-1)
+	}
+	return line
+}
+
+func (p *picker) render() {
+	width, height := terminalSize(p.out.Fd())
+
+	// keep room for the query, the counter and details of selected module
+	rows := min(pickerMaxHeight, len(p.matches))
+	if height > 0 {
+		rows = max(min(rows, height-6), 1)
+	}
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	}
+	if p.cursor >= p.offset+rows {
+		p.offset = p.cursor - rows + 1
+	}
+
+	lines := []string{
+		color.BlueString("❯ ") + string(p.query),
+		color.BlackString("  %d/%d modules", len(p.matches), len(p.entries)),
+	}
+
+	visible := p.matches[p.offset:min(p.offset+rows, len(p.matches))]
+	nameWidth := 0
+	for _, i := range visible {
+		nameWidth = max(nameWidth, utf8.RuneCountInString(p.entries[i].Name))
+	}
+	for j, i := range visible {
+		e := p.entries[i]
+		name := e.Name + strings.Repeat(" ", nameWidth-utf8.RuneCountInString(e.Name))
+		if p.offset+j == p.cursor {
+			lines = append(lines, color.CyanString("▶ %s", name)+"  "+color.BlackString(summary(e)))
+		} else {
+			lines = append(lines, "  "+name+"  "+color.BlackString(summary(e)))
+		}
+	}
+
+	if len(p.matches) > 0 {
+		e := p.entries[p.matches[p.cursor]]
+		for _, step := range e.Steps {
+			lines = append(lines, color.BlackString("  $ %s", strings.ReplaceAll(step.String(), "\n  ", "; ")))
+		}
+		if e.Dir != "" {
+			lines = append(lines, color.BlackString("  @%s", e.Dir))
+		}
+		if len(e.Dependencies) > 0 {
+			lines = append(lines, color.BlackString("  depends on %s", strings.Join(e.Dependencies, ", ")))
+		}
+	}
+	if height > 0 && len(lines) > height-1 {
+		lines = lines[:height-1]
+	}
+
+	// cursor is kept at the query line between renders
+	buf := strings.Builder{}
+	buf.WriteString("\r\x1b[J")
+	for i, line := range lines {
+		if i > 0 {
+			// raw mode doesn't translate line feeds
+			buf.WriteString("\r\n")
+		}
+		buf.WriteString(truncate(line, width))
+	}
+
+	// put the cursor back to the end of the query
+	if len(lines) > 1 {
+		buf.WriteString(fmt.Sprintf("\x1b[%dA", len(lines)-1))
+	}
+	buf.WriteString(fmt.Sprintf("\r\x1b[%dC", 2+len(p.query)))
+	fmt.Fprint(p.out, buf.String())
+}
+
+// clear erases the picker.
+func (p *picker) clear() {
+	fmt.Fprint(p.out, "\r\x1b[J")
+}
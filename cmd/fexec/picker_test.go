@@ -0,0 +1,127 @@
+package main
+
+import (
+	"testing"
+
+	framework "github.com/roboslone/go-framework/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPicker(names ...string) *picker {
+	p := &picker{}
+	for _, name := range names {
+		p.entries = append(p.entries, ListEntry{Name: name})
+	}
+	p.filter()
+	return p
+}
+
+func TestPicker_Handle(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		input    string
+		query    string
+		cursor   int
+		selected string
+		err      error
+	}{
+		{name: "arrow down", input: "\x1b[B", cursor: 1},
+		{name: "arrow up stops at first", input: "\x1b[A\x1b[A", cursor: 0},
+		{name: "SS3 arrows", input: "\x1bOB\x1bOB\x1bOA", cursor: 1},
+		{name: "page down stops at last", input: "\x1b[6~", cursor: 3},
+		{name: "page up", input: "\x1b[6~\x1b[5~", cursor: 0},
+		{name: "ctrl+n and ctrl+p", input: "\x0e\x0e\t\x10", cursor: 2},
+		{name: "unknown sequence is consumed", input: "\x1b[1;5Cb", query: "b"},
+		{name: "incomplete sequence is dropped", input: "\x1b[1;5", query: ""},
+		{name: "enter selects", input: "\x1b[B\x1b[B\r", cursor: 2, selected: "test"},
+		{name: "line feed selects", input: "\n", selected: "build"},
+		{name: "query", input: "tst\r", query: "tst", selected: "test"},
+		{name: "utf-8", input: "lïnt", query: "lïnt"},
+		{name: "backspace", input: "ab\x7f", query: "a"},
+		{name: "backspace removes whole rune", input: "aï\x08", query: "a"},
+		{name: "ctrl+u", input: "ab\x15c", query: "c"},
+		{name: "control characters are ignored", input: "\x01a\x02", query: "a"},
+		{name: "enter without matches", input: "xyz\r", query: "xyz"},
+		{name: "escape", input: "a\x1b", query: "a", err: errPickCancelled},
+		{name: "ctrl+c", input: "\x03", err: errPickCancelled},
+		{name: "ctrl+d", input: "\x04", err: errPickCancelled},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p := newTestPicker("build", "lint", "test", "deploy")
+
+			selected, err := p.handle([]byte(tc.input))
+			require.ErrorIs(t, err, tc.err)
+			require.Equal(t, tc.selected, selected)
+			require.Equal(t, tc.query, string(p.query))
+			require.Equal(t, tc.cursor, p.cursor)
+		})
+	}
+}
+
+func TestPicker_Filter(t *testing.T) {
+	p := newTestPicker("build", "lint-tests", "tests", "cmd/test-e2e", "test")
+	p.entries = append(p.entries, ListEntry{
+		Name:  "unit",
+		Steps: []framework.CommandStep{{Command: []string{"go", "test", "./..."}}},
+	})
+
+	names := func() []string {
+		var result []string
+		for _, i := range p.matches {
+			result = append(result, p.entries[i].Name)
+		}
+		return result
+	}
+
+	p.filter()
+	require.Equal(t, []string{"build", "lint-tests", "tests", "cmd/test-e2e", "test", "unit"}, names())
+
+	p.cursor = 2
+	_, err := p.handle([]byte("test"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"test", "tests", "cmd/test-e2e", "lint-tests", "unit"}, names())
+	require.Zero(t, p.cursor, "cursor is reset by filtering")
+
+	_, err = p.handle([]byte("\x15BLD"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"build"}, names())
+}
+
+func TestFuzzyScore(t *testing.T) {
+	for _, tc := range []struct {
+		query     string
+		candidate string
+		ok        bool
+	}{
+		{query: "", candidate: "build", ok: true},
+		{query: "bld", candidate: "build", ok: true},
+		{query: "BLD", candidate: "build", ok: true},
+		{query: "bld", candidate: "BUILD", ok: true},
+		{query: "ïn", candidate: "lïnt", ok: true},
+		{query: "db", candidate: "build", ok: false},
+		{query: "builds", candidate: "build", ok: false},
+	} {
+		t.Run(tc.query+"/"+tc.candidate, func(t *testing.T) {
+			_, ok := fuzzyScore([]rune(tc.query), tc.candidate)
+			require.Equal(t, tc.ok, ok)
+		})
+	}
+
+	// better matches first
+	for _, tc := range []struct {
+		query   string
+		ranking []string
+	}{
+		{query: "te", ranking: []string{"test", "tests", "testing"}},   // shorter candidates win ties
+		{query: "te", ranking: []string{"cmd/test", "contest"}},        // word boundaries
+		{query: "bu", ranking: []string{"build", "bench-ui", "bonus"}}, // consecutive matches
+	} {
+		var scores []int
+		for _, candidate := range tc.ranking {
+			score, ok := fuzzyScore([]rune(tc.query), candidate)
+			require.True(t, ok, candidate)
+			scores = append(scores, score)
+		}
+		require.IsDecreasing(t, scores, "%q: %v", tc.query, tc.ranking)
+	}
+}
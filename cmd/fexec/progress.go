@@ -0,0 +1,318 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"github.com/roboslone/go-framework/v2"
+)
+
+const (
+	progressInterval = 100 * time.Millisecond
+)
+
+var (
+	spinner = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+)
+
+// ProgressView renders state of every module in the topology, redrawing it in place.
+// It's meant to be used as a `framework.Reporter` when stdout is a terminal.
+type ProgressView struct {
+	lock     sync.Mutex
+	out      io.Writer
+	fd       uintptr
+	topology *framework.Topology
+	modules  framework.Modules
+	console  *framework.ConsoleReporter
+	states   map[string]*progressState
+	warnings []string
+	drawn    int
+	frame    int
+	finished bool
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+type progressState struct {
+	started time.Time
+	last    string
+	finish  *framework.FinishEvent
+}
+
+func NewProgressView(out *os.File, topology *framework.Topology, modules framework.Modules, verbose, usage bool) *ProgressView {
+	v := &ProgressView{
+		out:      out,
+		fd:       out.Fd(),
+		topology: topology,
+		modules:  modules,
+		console:  &framework.ConsoleReporter{Verbose: verbose, Usage: usage},
+		states:   make(map[string]*progressState, len(topology.OrderedModuleNames)),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	for _, name := range topology.OrderedModuleNames {
+		v.states[name] = &progressState{}
+	}
+	return v
+}
+
+func (v *ProgressView) Report(module string, event framework.Event) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	state, ok := v.states[module]
+	if !ok {
+		state = &progressState{}
+		v.states[module] = state
+	}
+
+	switch e := event.(type) {
+	case *framework.StartEvent:
+		state.started = e.Time
+	case *framework.OutputEvent:
+		state.last = e.Line
+	case *framework.WarningEvent:
+		v.warnings = append(v.warnings, fmt.Sprintf("%s: %s", module, e.Message))
+	case *framework.FinishEvent:
+		state.finish = e
+	}
+}
+
+// Start begins redrawing the view periodically.
+func (v *ProgressView) Start() {
+	go func() {
+		defer close(v.stopped)
+
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+
+		for {
+			v.render()
+
+			select {
+			case <-ticker.C:
+			case <-v.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop draws the final state of the view, then prints commands & output of failed modules
+// (or of all modules, if verbose) the same way `framework.ConsoleReporter` does.
+func (v *ProgressView) Stop() {
+	close(v.stop)
+	<-v.stopped
+
+	v.lock.Lock()
+	v.finished = true
+	v.lock.Unlock()
+	v.render()
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	for _, w := range v.warnings {
+		fmt.Fprintln(v.out, color.YellowString("⚠ %s", w))
+	}
+
+	for _, name := range v.topology.OrderedModuleNames {
+		finish := v.states[name].finish
+		if finish == nil || finish.Status == framework.StatusSkipped {
+			continue
+		}
+		if finish.Status == framework.StatusFailed || v.console.Verbose {
+			fmt.Fprintln(v.out)
+			v.console.Report(name, finish)
+		}
+	}
+}
+
+func (v *ProgressView) render() {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	width, height := terminalSize(v.fd)
+	lines := v.lines()
+	if limit := height - 1; limit > 0 && len(lines) > limit {
+		lines = append(lines[:limit-1], color.BlackString("… %d more", len(lines)-limit+1))
+	}
+
+	buf := strings.Builder{}
+	if v.drawn > 0 {
+		buf.WriteString(fmt.Sprintf("\x1b[%dA", v.drawn))
+	}
+	buf.WriteString("\r\x1b[J")
+	for _, line := range lines {
+		buf.WriteString(truncate(line, width))
+		buf.WriteString("\n")
+	}
+	if !framework.DefaultTerminal.TryPrint(func() { fmt.Fprint(v.out, buf.String()) }) {
+		// an interactive command owns the terminal, draw below its output once it's done
+		v.drawn = 0
+		return
+	}
+
+	v.drawn = len(lines)
+	v.frame++
+}
+
+// lines describe each module: running and failed modules first, then waiting and finished ones.
+func (v *ProgressView) lines() []string {
+	var running, waiting, finished []string
+
+	for _, name := range v.topology.OrderedModuleNames {
+		state := v.states[name]
+
+		switch v.status(name) {
+		case progressRunning:
+			elapsed := time.Since(state.started).Truncate(100 * time.Millisecond)
+			running = append(running, fmt.Sprintf(
+				"%s %s %s %s",
+				color.BlueString(spinner[v.frame%len(spinner)]),
+				name,
+				color.BlackString(elapsed.String()),
+				color.BlackString(state.last),
+			))
+
+		case progressFailed:
+			running = append(running, fmt.Sprintf(
+				"%s %s %s",
+				color.RedString("❌"),
+				name,
+				color.BlackString(v.summary(state.finish)),
+			))
+
+		case progressBlocked:
+			finished = append(finished, fmt.Sprintf("%s %s %s", color.BlackString("–"), name, color.BlackString("dependency failed")))
+
+		case progressWaiting:
+			if v.finished {
+				finished = append(finished, fmt.Sprintf("%s %s %s", color.BlackString("–"), name, color.BlackString("not run")))
+				continue
+			}
+
+			var pending []string
+			for _, d := range v.topology.DirectDependencies[name] {
+				if s := v.status(d); s != progressSucceeded && s != progressSkipped {
+					pending = append(pending, d)
+				}
+			}
+			waiting = append(waiting, fmt.Sprintf(
+				"%s %s %s",
+				color.BlackString("…"),
+				name,
+				color.BlackString("waiting for %s", strings.Join(pending, ", ")),
+			))
+
+		case progressSkipped:
+			finished = append(finished, fmt.Sprintf(
+				"%s %s %s",
+				color.YellowString("↷"),
+				name,
+				color.BlackString("skipped: %s", state.finish.Reason),
+			))
+
+		case progressSucceeded:
+			var duration string
+			if state.finish != nil {
+				duration = v.summary(state.finish)
+			}
+			finished = append(finished, fmt.Sprintf("%s %s %s", color.GreenString("✓"), name, color.BlackString(duration)))
+		}
+	}
+
+	return append(append(running, waiting...), finished...)
+}
+
+// summary returns duration of finished module, along with its resource usage if enabled.
+func (v *ProgressView) summary(finish *framework.FinishEvent) string {
+	s := finish.Duration.Round(time.Millisecond).String()
+	if v.console.Usage {
+		s += " " + finish.Usage.String()
+	}
+	return s
+}
+
+type progressStatus int
+
+const (
+	progressWaiting progressStatus = iota
+	progressRunning
+	progressSucceeded
+	progressSkipped
+	progressFailed
+	progressBlocked
+)
+
+func (v *ProgressView) status(name string) progressStatus {
+	state := v.states[name]
+	if state.finish != nil {
+		switch state.finish.Status {
+		case framework.StatusFailed:
+			return progressFailed
+		case framework.StatusSkipped:
+			return progressSkipped
+		default:
+			return progressSucceeded
+		}
+	}
+	if !state.started.IsZero() {
+		return progressRunning
+	}
+
+	done := true
+	for _, d := range v.topology.DirectDependencies[name] {
+		switch v.status(d) {
+		case progressFailed, progressBlocked:
+			return progressBlocked
+		case progressSucceeded, progressSkipped:
+		default:
+			done = false
+		}
+	}
+
+	// modules without commands succeed as soon as their dependencies do
+	if _, ok := v.modules[name].(*framework.NoopModule); ok && done {
+		return progressSucceeded
+	}
+	return progressWaiting
+}
+
+// truncate cuts visible part of the line to given width.
+// Color sequences are kept, other escape sequences and control characters are dropped.
+func truncate(line string, width int) string {
+	result := strings.Builder{}
+	visible := 0
+	for i := 0; i < len(line); {
+		if strings.HasPrefix(line[i:], "\x1b[") {
+			end := i + 2
+			for end < len(line) && (line[end] < 0x40 || line[end] > 0x7e) {
+				end++
+			}
+			if end < len(line) && line[end] == 'm' {
+				result.WriteString(line[i : end+1])
+			}
+			i = end + 1
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(line[i:])
+		i += size
+		if r < 0x20 || r == 0x7f {
+			r = ' '
+		}
+		if width > 0 && visible >= width-1 {
+			continue
+		}
+		result.WriteRune(r)
+		visible++
+	}
+	return result.String()
+}
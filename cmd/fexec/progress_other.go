@@ -0,0 +1,7 @@
+//go:build !unix
+
+package main
+
+func terminalSize(uintptr) (int, int) {
+	return 0, 0
+}
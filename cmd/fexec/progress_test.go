@@ -0,0 +1,155 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	framework "github.com/roboslone/go-framework/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestProgressView returns a view of a fake topology:
+//
+//	build - test - deploy
+//	lint
+//	all (noop) - test, lint
+func newTestProgressView(t *testing.T) *ProgressView {
+	t.Helper()
+
+	color.NoColor = true
+
+	out, err := os.Create(filepath.Join(t.TempDir(), "progress"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = out.Close() })
+
+	topology := &framework.Topology{
+		RequestedModuleNames: []string{"deploy", "all"},
+		OrderedModuleNames:   []string{"build", "lint", "test", "deploy", "all"},
+		DirectDependencies: map[string][]string{
+			"test":   {"build"},
+			"deploy": {"test"},
+			"all":    {"test", "lint"},
+		},
+	}
+	modules := framework.Modules{
+		"build":  &framework.CommandModule[any]{},
+		"lint":   &framework.CommandModule[any]{},
+		"test":   &framework.CommandModule[any]{},
+		"deploy": &framework.CommandModule[any]{},
+		"all":    &framework.NoopModule{DependsOn: []string{"test", "lint"}},
+	}
+	return NewProgressView(out, topology, modules, false, false)
+}
+
+// headers returns icon and module name of each line.
+func headers(lines []string) []string {
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		result = append(result, strings.Join(fields[:2], " "))
+	}
+	return result
+}
+
+func TestProgressView_Status(t *testing.T) {
+	v := newTestProgressView(t)
+
+	statuses := func() map[string]progressStatus {
+		result := make(map[string]progressStatus)
+		for _, name := range v.topology.OrderedModuleNames {
+			result[name] = v.status(name)
+		}
+		return result
+	}
+
+	require.Equal(t, map[string]progressStatus{
+		"build":  progressWaiting,
+		"lint":   progressWaiting,
+		"test":   progressWaiting,
+		"deploy": progressWaiting,
+		"all":    progressWaiting,
+	}, statuses())
+
+	v.Report("build", &framework.StartEvent{Time: time.Now()})
+	v.Report("lint", &framework.StartEvent{Time: time.Now()})
+	require.Equal(t, progressRunning, v.status("build"))
+	require.Equal(t, progressRunning, v.status("lint"))
+	require.Equal(t, progressWaiting, v.status("test"))
+
+	v.Report("build", &framework.FinishEvent{Status: framework.StatusSucceeded})
+	v.Report("lint", &framework.FinishEvent{Status: framework.StatusSkipped, Reason: "unchanged"})
+	require.Equal(t, progressSucceeded, v.status("build"))
+	require.Equal(t, progressSkipped, v.status("lint"))
+	require.Equal(t, progressWaiting, v.status("test"))
+	require.Equal(t, progressWaiting, v.status("all"), "noop module waits for all of its dependencies")
+
+	v.Report("test", &framework.StartEvent{Time: time.Now()})
+	v.Report("test", &framework.FinishEvent{Status: framework.StatusFailed})
+	require.Equal(t, map[string]progressStatus{
+		"build":  progressSucceeded,
+		"lint":   progressSkipped,
+		"test":   progressFailed,
+		"deploy": progressBlocked,
+		"all":    progressBlocked,
+	}, statuses())
+}
+
+func TestProgressView_NoopSucceeds(t *testing.T) {
+	v := newTestProgressView(t)
+
+	v.Report("build", &framework.FinishEvent{Status: framework.StatusSucceeded})
+	v.Report("test", &framework.FinishEvent{Status: framework.StatusSucceeded})
+	require.Equal(t, progressWaiting, v.status("all"))
+
+	v.Report("lint", &framework.FinishEvent{Status: framework.StatusSucceeded})
+	require.Equal(t, progressSucceeded, v.status("all"))
+	require.Equal(t, progressWaiting, v.status("deploy"))
+}
+
+func TestProgressView_Lines(t *testing.T) {
+	v := newTestProgressView(t)
+
+	lines := v.lines()
+	require.Equal(t, []string{"… build", "… lint", "… test", "… deploy", "… all"}, headers(lines))
+	require.Contains(t, lines[2], "waiting for build")
+	require.Contains(t, lines[4], "waiting for test, lint")
+
+	v.Report("build", &framework.StartEvent{Time: time.Now()})
+	v.Report("build", &framework.OutputEvent{Line: "compiling"})
+	v.Report("lint", &framework.FinishEvent{Status: framework.StatusSucceeded, Duration: 1500 * time.Millisecond})
+
+	lines = v.lines()
+	require.Equal(t, []string{"⠋ build", "… test", "… deploy", "… all", "✓ lint"}, headers(lines))
+	require.True(t, strings.HasSuffix(lines[0], " compiling"), lines[0])
+	require.Contains(t, lines[3], "waiting for test")
+	require.NotContains(t, lines[3], "lint")
+	require.Contains(t, lines[4], "1.5s")
+
+	v.frame++
+	require.Equal(t, "⠙ build", headers(v.lines())[0], "spinner advances with frames")
+
+	v.Report("build", &framework.FinishEvent{Status: framework.StatusFailed, Duration: time.Second})
+
+	lines = v.lines()
+	require.Equal(t, []string{"❌ build", "✓ lint", "– test", "– deploy", "– all"}, headers(lines))
+	require.Contains(t, lines[0], "1s")
+	require.Contains(t, lines[2], "dependency failed")
+}
+
+func TestProgressView_LinesFinished(t *testing.T) {
+	v := newTestProgressView(t)
+
+	v.Report("build", &framework.FinishEvent{Status: framework.StatusSucceeded, Duration: time.Second})
+	v.Report("lint", &framework.FinishEvent{Status: framework.StatusSkipped, Reason: "unchanged"})
+	v.finished = true
+
+	// e.g. the run was interrupted before remaining modules started
+	lines := v.lines()
+	require.Equal(t, []string{"✓ build", "↷ lint", "– test", "– deploy", "– all"}, headers(lines))
+	require.Contains(t, lines[1], "skipped: unchanged")
+	require.Contains(t, lines[2], "not run")
+}
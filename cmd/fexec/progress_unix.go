@@ -0,0 +1,13 @@
+//go:build unix
+
+package main
+
+import "golang.org/x/sys/unix"
+
+func terminalSize(fd uintptr) (int, int) {
+	ws, err := unix.IoctlGetWinsize(int(fd), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0, 0
+	}
+	return int(ws.Col), int(ws.Row)
+}
@@ -0,0 +1,32 @@
+package main
+
+//go:generate go run . schema -o ../../fexec.schema.json
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+
+	"github.com/roboslone/go-framework/v2/fexec"
+)
+
+// PrintSchema prints JSON Schema of config file, see `fexec.Schema`.
+func PrintSchema(args []string) error {
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
+	output := fs.String("o", "", "Write schema to file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(fexec.Schema(), "", "  ")
+	if err != nil {
+		return err
+	}
+	content = append(content, '\n')
+
+	if *output == "" {
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+	return os.WriteFile(*output, content, 0o644)
+}
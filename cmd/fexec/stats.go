@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/roboslone/go-framework/v2"
+	"github.com/roboslone/go-framework/v2/fexec"
+)
+
+// Stats prints duration percentiles and failure rates of modules recorded in history,
+// along with the critical path through given modules (all modules by default).
+func Stats(ctx context.Context, cfg *fexec.CommandConfig, historyPath string, args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	last := fs.Int("n", 100, "Only consider `N` most recent runs")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	runs, err := fexec.ReadHistory(historyPath)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		return fmt.Errorf("no runs recorded in %s", historyPath)
+	}
+	runs = runs[max(len(runs)-*last, 0):]
+
+	modules := make(framework.Modules, len(cfg.Commands))
+	for name, c := range cfg.Commands {
+		modules[name] = &framework.NoopModule{DependsOn: c.DependsOn}
+	}
+	app := framework.NewApplication[any]("fexec", modules)
+
+	names := fs.Args()
+	if len(names) == 0 {
+		names = []string{"*"}
+	}
+	if names, err = app.Glob(names...); err != nil {
+		return err
+	}
+	topology, err := app.BuildTopology(ctx, names...)
+	if err != nil {
+		return fmt.Errorf("building topology: %w", err)
+	}
+
+	stats := make(map[string]fexec.ModuleStats)
+	for _, s := range fexec.ComputeStats(runs) {
+		stats[s.Module] = s
+	}
+
+	fmt.Println(color.BlackString("%d runs, %s – %s", len(runs), runs[0].Started.Format(time.DateTime), runs[len(runs)-1].Started.Format(time.DateTime)))
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODULE\tRUNS\tFAILED\tFLAKY\tP50\tP95\tMAX RSS")
+	for _, name := range topology.OrderedModuleNames {
+		s, ok := stats[name]
+		if !ok {
+			continue
+		}
+		maxRSS := "-"
+		if s.MaxRSS > 0 {
+			maxRSS = s.MaxRSS.String()
+		}
+		fmt.Fprintf(
+			w, "%s\t%d\t%d\t%.0f%%\t%s\t%s\t%s\n",
+			name, s.Runs, s.Failures, s.FlakyRate()*100, round(s.P50), round(s.P95), maxRSS,
+		)
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+
+	path, total := topology.CriticalPath(func(module string) time.Duration {
+		return stats[module].P50
+	})
+	steps := make([]string, 0, len(path))
+	for _, name := range path {
+		if s, ok := stats[name]; ok {
+			steps = append(steps, fmt.Sprintf("%s %s", name, color.BlackString(round(s.P50))))
+		} else {
+			steps = append(steps, name)
+		}
+	}
+	fmt.Printf("\nCritical path (p50): %s = %s\n", strings.Join(steps, " → "), round(total))
+	return nil
+}
+
+func round(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
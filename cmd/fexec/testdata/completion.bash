@@ -0,0 +1,13 @@
+_fexec() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local words="$(fexec list 2>/dev/null)"
+    if [ "${COMP_CWORD}" -eq 1 ]; then
+        words="${words} list describe validate schema stats init import completion"
+    fi
+    COMPREPLY=($(compgen -W "${words}" -- "${cur}"))
+    # module names may contain colons, which bash treats as word breaks
+    if declare -F __ltrim_colon_completions >/dev/null; then
+        __ltrim_colon_completions "${cur}"
+    fi
+}
+complete -F _fexec fexec
@@ -0,0 +1,2 @@
+complete -c fexec -f -a '(fexec list 2>/dev/null)'
+complete -c fexec -f -n '__fish_use_subcommand' -a 'list describe validate schema stats init import completion'
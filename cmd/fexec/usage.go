@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/roboslone/go-framework/v2"
+	"github.com/roboslone/go-framework/v2/fexec"
+)
+
+// PrintUsage prints variables and commands of the config.
+func PrintUsage(cfg *fexec.CommandConfig, configPath string) {
+	result := strings.Builder{}
+
+	result.WriteString(color.BlackString("\n@%s\n", configPath))
+
+	if len(cfg.Vars) > 0 {
+		result.WriteString("Variables:\n")
+		for _, name := range slices.Sorted(maps.Keys(cfg.Vars)) {
+			v := cfg.Vars[name]
+			if v.Sh != "" {
+				result.WriteString(fmt.Sprintf("\t%s %s\n", name, color.BlackString("$(%s)", v.Sh)))
+			} else {
+				result.WriteString(fmt.Sprintf("\t%s %s\n", name, color.BlackString("= %s", v.Value)))
+			}
+		}
+	}
+
+	result.WriteString(fmt.Sprintf("Subcommands: %s\n", strings.Join(Subcommands, ", ")))
+	result.WriteString("Available modules:\n")
+
+	for _, name := range slices.Sorted(maps.Keys(cfg.Commands)) {
+		result.WriteString(fmt.Sprintf("\t%s\n", name))
+
+		module := cfg.Commands[name]
+		if len(module.Command) > 0 || module.Script != "" {
+			step := framework.CommandStep{Command: module.Command, Script: module.Script}
+			result.WriteString(color.BlackString("\t\t$ %s\n", indent(step.String(), "\t\t")))
+		}
+		for i, step := range module.Steps {
+			if step.Name != "" {
+				result.WriteString(color.BlackString("\t\t%d. %s\n", i+1, step.Name))
+			} else {
+				result.WriteString(color.BlackString("\t\t%d.\n", i+1))
+			}
+			result.WriteString(color.BlackString("\t\t   $ %s\n", indent(step.String(), "\t\t   ")))
+		}
+		if module.Dir != "" {
+			result.WriteString(color.BlackString("\t\t@%s\n", module.Dir))
+		}
+		if len(module.DependsOn) > 0 {
+			result.WriteString(color.BlackString("\t\tdepends on %s\n", strings.Join(module.DependsOn, ", ")))
+		}
+	}
+
+	fmt.Println(result.String())
+}
+
+func indent(s, prefix string) string {
+	return strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
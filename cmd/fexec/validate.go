@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/roboslone/go-framework/v2/fexec"
+)
+
+// Validate parses config at given path along with all included configs, and prints every problem found.
+// Commands shadowed by subcommands are reported as well.
+func Validate(path string) error {
+	cfg, err := fexec.ParseConfig(path)
+	if err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Fprintln(os.Stderr, color.RedString(line))
+		}
+		return errors.New("config is invalid")
+	}
+
+	if shadowed := shadowedCommands(cfg); len(shadowed) > 0 {
+		for _, name := range shadowed {
+			fmt.Fprintln(os.Stderr, color.RedString("%q: shadowed by subcommand `fexec %s`, rename the command", name, name))
+		}
+		return errors.New("config is invalid")
+	}
+
+	fmt.Printf("%s %s %s\n", color.GreenString("✓"), path, color.BlackString("%d commands", len(cfg.Commands)))
+	return nil
+}
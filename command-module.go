@@ -1,16 +1,30 @@
 package framework
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
-
-	"github.com/fatih/color"
 )
 
+// DefaultKillGrace is used, unless `CommandModule.KillGrace` is set.
+var DefaultKillGrace = 5 * time.Second
+
+// CommandModule runs a command (or a script, or several steps) in its own process group.
+//
+// Once context is cancelled, the process group receives a signal: either the one from `SignalCause`,
+// or SIGTERM. Processes still running after KillGrace (or another signal, see `NotifyContext`) are killed.
 type CommandModule[State any] struct {
 	Command   []string `yaml:"command"`
 	Dir       string   `yaml:"dir"`
@@ -19,90 +33,780 @@ type CommandModule[State any] struct {
 	Verbose   bool     `yaml:"verbose"`
 	Live      bool     `yaml:"live"`
 
+	// Vars are substituted in command, dir and env as `$NAME` or `${NAME}`.
+	// Names not found in Vars are looked up in process environment.
+	Vars map[string]string `yaml:"vars"`
+
+	// EnvFiles are dotenv files loaded in order before Env, see `ParseEnv`.
+	// Relative paths are resolved against Dir, missing files are reported and skipped.
+	EnvFiles []string `yaml:"env_file"`
+
+	// CleanEnv disables inheritance of process environment, except for variables listed in PassEnv.
+	CleanEnv bool `yaml:"clean_env"`
+
+	// PassEnv lists names of process environment variables passed to the command when CleanEnv is set.
+	// Names are matched using `filepath.Match`, e.g. `GO*`.
+	PassEnv []string `yaml:"pass_env"`
+
+	// Secrets lists names of variables (Vars, outputs or environment), which values are replaced with
+	// `SecretMask` in everything the module reports: output, commands, published outputs and warnings.
+	Secrets []string `yaml:"secrets"`
+
+	// Mask lists regular expressions, which matches are masked the same way as Secrets.
+	Mask []string `yaml:"mask"`
+
+	// Output is a name of output, that receives trimmed stdout of the command.
+	Output string `yaml:"output"`
+
+	// Outputs receive values published by the command: `Output` and `KEY=VALUE` lines written
+	// to a file at `$FEXEC_OUTPUT`. Published values can be referenced by other modules as `${module.KEY}`,
+	// the command fails if a dependency ran, but didn't publish a referenced value. Outputs of skipped modules
+	// are empty. Outputs are not published if nil.
+	Outputs *Outputs `yaml:"-"`
+
 	// ErrorOnOutput controls whether the module should fail if any output was produced by the command.
 	// This can be helpful for tools like `deadcode`.
 	ErrorOnOutput bool `yaml:"error_on_output"`
+
+	// Script is run using Shell instead of Command, see `CommandStep`.
+	Script string `yaml:"script"`
+
+	// Shell runs scripts, script is appended as the last argument. Defaults to `DefaultShell`.
+	Shell []string `yaml:"shell"`
+
+	// Steps are run sequentially instead of Command or Script, sharing Dir and environment.
+	// Module fails on the first failed step.
+	Steps []CommandStep `yaml:"steps"`
+
+	// When must hold for the command to run, SkipIf must not.
+	// Skipped module succeeds, so its dependents are run.
+	When   *Condition `yaml:"when"`
+	SkipIf *Condition `yaml:"skip_if"`
+
+	// TTY runs command under a pseudo-terminal, so tools keep their colors and progress bars.
+	// Stdout and stderr are merged. Only supported on unix.
+	TTY bool `yaml:"tty"`
+
+	// KillGrace is a time between termination signal and SIGKILL sent to command process group
+	// once context is cancelled. Defaults to `DefaultKillGrace`.
+	KillGrace time.Duration `yaml:"kill_grace"`
+
+	// MaxMemory fails the command, if peak RSS of any of its processes exceeds it, see `Usage`.
+	// The limit is soft: it's checked once the command exits. Only supported on unix.
+	MaxMemory ByteSize `yaml:"max_memory"`
+
+	// TmpDir creates a fresh temp directory for each run, its path is available as `$FEXEC_TMP`
+	// in the command and its env. The directory is removed once the command finishes.
+	TmpDir bool `yaml:"tmpdir"`
+
+	// WorkdirCopy runs the command in a throwaway copy of the repository containing Dir, so that commands
+	// writing to the tree don't race with each other. Git repositories are checked out as a worktree
+	// with uncommitted changes copied over, ignored files are not copied. Conditions and env files
+	// are still evaluated in Dir. The copy is removed once the command finishes.
+	WorkdirCopy bool `yaml:"workdir_copy"`
+
+	// Stdin is an input of each step, see `Input`. By default commands read nothing.
+	Stdin *Input `yaml:"stdin"`
+
+	// Interactive connects the command directly to the terminal: it reads stdin of the process (unless Stdin is set)
+	// and writes to its stdout and stderr, so its output is neither captured nor masked. Interactive commands
+	// run one at a time, while output of other modules is held back, see `Terminal`.
+	Interactive bool `yaml:"interactive"`
+
+	// Reporter receives events of the module, defaults to `ConsoleReporter` configured with Verbose and Live.
+	Reporter Reporter `yaml:"-"`
 }
 
-func (m *CommandModule[State]) Start(ctx context.Context, _ *State) error {
-	str := strings.Join(m.Command, " ")
+func (m *CommandModule[State]) Start(ctx context.Context, _ *State) (err error) {
+	name := GetModuleName(ctx)
+	reporter := m.reporter()
+	start := time.Now()
+
+	// errors returned before the command runs are reported the same way as failures of the command
+	finished := false
+	defer func() {
+		if err != nil && !finished {
+			reporter.Report(name, &FinishEvent{
+				Time:     time.Now(),
+				Status:   StatusFailed,
+				Duration: time.Since(start),
+				Err:      err,
+			})
+		}
+	}()
 
-	args := make([]string, 0, len(m.Command)-1)
-	for _, s := range m.Command[1:] {
-		args = append(args, os.ExpandEnv(s))
+	dir := m.expand(m.Dir, nil)
+
+	var extra []string
+	if m.TmpDir {
+		tmpDir, err := createTempDir(name, "tmp")
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := os.RemoveAll(tmpDir); err != nil {
+				reporter.Report(name, &WarningEvent{Time: time.Now(), Message: fmt.Sprintf("removing temp dir: %s", err)})
+			}
+		}()
+		extra = append(extra, TmpDirEnv+"="+tmpDir)
 	}
 
-	cmd := exec.CommandContext(ctx, m.Command[0], args...)
-	cmd.Dir = m.Dir
-	cmd.Env = append(os.Environ(), m.Env...)
+	env, err := m.environ(ctx, dir, extra, reporter)
+	if err != nil {
+		return err
+	}
 
-	if m.Verbose {
-		fmt.Printf(
-			"%s %s %s\n",
-			color.BlueString("↪︎"),
-			GetModuleName(ctx),
-			color.BlackString("starting..."),
-		)
+	masker, short, err := m.masker(env)
+	if err != nil {
+		return err
+	}
+	reporter = &maskingReporter{reporter: reporter, masker: masker}
+	// returned errors end up in logs and notifications, same as reported ones
+	defer func() { err = masker.Error(err) }()
+	for _, secret := range short {
+		reporter.Report(name, &WarningEvent{
+			Time:    time.Now(),
+			Message: fmt.Sprintf("secret %s is too short to be masked", secret),
+		})
 	}
 
-	start := time.Now()
+	var outputFile string
+	if m.Outputs != nil {
+		if outputFile, err = createOutputFile(); err != nil {
+			return err
+		}
+		defer os.Remove(outputFile)
+		env = append(env, OutputFileEnv+"="+outputFile)
+	}
+
+	if skip, reason, err := m.skip(ctx, dir, env); err != nil {
+		return fmt.Errorf("evaluating condition: %w", err)
+	} else if skip {
+		if m.Outputs != nil {
+			m.Outputs.Skip(name)
+		}
+
+		finished = true
+		reporter.Report(name, &FinishEvent{
+			Time:     time.Now(),
+			Status:   StatusSkipped,
+			Duration: time.Since(start),
+			Reason:   reason,
+		})
+		return nil
+	}
+
+	if err = m.checkOutputs(name, reporter); err != nil {
+		return err
+	}
+
+	if m.MaxMemory > 0 && !maxMemorySupported {
+		reporter.Report(name, &WarningEvent{
+			Time:    time.Now(),
+			Message: fmt.Sprintf("max_memory is not supported on %s, ignoring it", runtime.GOOS),
+		})
+	}
+
+	var sourceDir string
+	if m.WorkdirCopy {
+		var workdir *workdirCopy
+		sourceDir = dir
+		if workdir, dir, err = copyWorkdir(ctx, name, dir); err != nil {
+			return fmt.Errorf("copying workdir: %w", err)
+		}
+		defer func() {
+			// the copy is removed even if the run was interrupted
+			if err := workdir.remove(context.WithoutCancel(ctx)); err != nil {
+				reporter.Report(name, &WarningEvent{Time: time.Now(), Message: err.Error()})
+			}
+		}()
+	}
+
+	steps := m.steps()
+	interactive := m.Interactive || m.Stdin.inheritsTerminal()
+
+	reporter.Report(name, &StartEvent{Time: time.Now(), Dir: dir, SourceDir: sourceDir, Steps: steps, Interactive: interactive})
+
+	var release func()
+	if interactive {
+		if release, err = DefaultTerminal.Acquire(ctx); err != nil {
+			err = fmt.Errorf("waiting for terminal: %w", err)
+		}
+	}
+
+	stdout := &bytes.Buffer{}
+	results := make([]StepResult, 0, len(steps))
+	var outputSize int
+	var usage Usage
+	for i, step := range steps {
+		if err != nil {
+			break
+		}
+
+		var label string
+		if len(steps) > 1 {
+			label = step.label(i)
+		}
+
+		r := m.runStep(ctx, step, label, dir, env, interactive, reporter, stdout)
+		results = append(results, r)
+		outputSize += len(r.Output)
+		usage = usage.Add(r.Usage)
+
+		if r.Err != nil {
+			err = r.Err
+			break
+		}
+	}
+	if release != nil {
+		release()
+	}
+
+	if err == nil && m.Outputs != nil {
+		err = m.publishOutputs(ctx, outputFile, stdout.Bytes())
+	}
+
+	if m.ErrorOnOutput && err == nil && outputSize > 0 {
+		err = fmt.Errorf("unexpected output (%d bytes)", outputSize)
+	}
+
+	finish := &FinishEvent{
+		Time:     time.Now(),
+		Status:   StatusSucceeded,
+		Duration: time.Since(start),
+		Err:      err,
+		Steps:    results,
+		Usage:    usage,
+	}
+	if err != nil {
+		finish.Status = StatusFailed
+	} else if m.Outputs != nil {
+		finish.Outputs = m.Outputs.All(name)
+	}
+	finished = true
+	reporter.Report(name, finish)
+
+	return err
+}
+
+// ResolvedCommand is a command module with variables substituted, see `CommandModule.Resolve`.
+type ResolvedCommand struct {
+	Dir string
+
+	// Env only contains variables set by env files and `Env`, inherited environment is omitted.
+	Env []string
+
+	Steps []CommandStep
+}
+
+// Resolve substitutes variables in dir, env and steps of the module without running it.
+// Outputs of other modules are only substituted if already published.
+func (m *CommandModule[State]) Resolve(ctx context.Context) (*ResolvedCommand, error) {
+	r := &ResolvedCommand{Dir: m.expand(m.Dir, nil)}
+
+	base := os.Environ()
+	if m.CleanEnv {
+		base = filterEnv(base, m.PassEnv)
+	}
 
-	var out []byte
 	var err error
-	if m.Live {
-		cmd.Stdout = NewPrefixedWriter(os.Stdout, color.BlackString("[%s] ", GetModuleName(ctx)))
-		cmd.Stderr = NewPrefixedWriter(os.Stderr, color.BlackString("[%s] ", GetModuleName(ctx)))
-		err = cmd.Run()
-	} else {
-		out, err = cmd.CombinedOutput()
+	if r.Env, err = m.ownEnviron(ctx, r.Dir, base, Reporters{}); err != nil {
+		return nil, err
 	}
+	env := append(base, r.Env...)
 
-	duration := time.Since(start).Round(time.Millisecond).String()
+	for _, step := range m.steps() {
+		if step.Script != "" {
+			step.Script = m.expandScript(step.Script)
+		}
+		step.Command = slices.Clone(step.Command)
+		for i, s := range step.Command {
+			step.Command[i] = m.expand(s, env)
+		}
+		r.Steps = append(r.Steps, step)
+	}
+	return r, nil
+}
 
-	if m.ErrorOnOutput && err == nil && len(out) > 0 {
-		err = fmt.Errorf("unexpected output (%d bytes)", len(out))
+// steps returns `Steps`, or a single step made of `Command` or `Script`.
+func (m *CommandModule[State]) steps() []CommandStep {
+	if len(m.Steps) > 0 {
+		return m.Steps
+	}
+	return []CommandStep{{Command: m.Command, Script: m.Script}}
+}
+
+func (m *CommandModule[State]) reporter() Reporter {
+	if m.Reporter != nil {
+		return m.Reporter
+	}
+	return &ConsoleReporter{Verbose: m.Verbose, Live: m.Live}
+}
+
+// skip evaluates When and SkipIf conditions.
+func (m *CommandModule[State]) skip(ctx context.Context, dir string, env []string) (bool, string, error) {
+	if m.When != nil {
+		ok, reason, err := m.When.Evaluate(ctx, dir, env, m.shell())
+		if err != nil || !ok {
+			return !ok, reason, err
+		}
 	}
 
+	if m.SkipIf != nil {
+		ok, _, err := m.SkipIf.Evaluate(ctx, dir, env, m.shell())
+		if err != nil || ok {
+			return ok, "skip_if holds", err
+		}
+	}
+
+	return false, "", nil
+}
+
+func (m *CommandModule[State]) shell() []string {
+	if len(m.Shell) == 0 {
+		return DefaultShell
+	}
+	return m.Shell
+}
+
+// runStep runs a single step, reporting its output line by line.
+// Stdout of the step is also written to `stdout`, if Output is set.
+func (m *CommandModule[State]) runStep(
+	ctx context.Context,
+	step CommandStep,
+	label string,
+	dir string,
+	env []string,
+	interactive bool,
+	reporter Reporter,
+	stdout io.Writer,
+) StepResult {
+	r := StepResult{Label: label, Command: step.String()}
+	start := time.Now()
+	name := GetModuleName(ctx)
+
+	var argv []string
+	if step.Script != "" {
+		argv = append(slices.Clone(m.shell()), m.expandScript(step.Script))
+	} else {
+		for _, s := range step.Command {
+			argv = append(argv, m.expand(s, env))
+		}
+	}
+	if len(argv) == 0 {
+		r.Err = fmt.Errorf("empty command")
+		return r
+	}
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = dir
+	cmd.Env = env
+	stopKill := m.terminateOnCancel(ctx, cmd, interactive)
+	defer stopKill()
+
+	stdin, closeStdin, err := m.Stdin.open(dir, func(s string) string { return m.expand(s, env) })
 	if err != nil {
-		fmt.Printf(
-			"%s %s %s\n",
-			color.RedString("❌"),
-			GetModuleName(ctx),
-			color.BlackString(duration),
-		)
+		r.Err = err
+		return r
+	}
+	defer closeStdin()
+	cmd.Stdin = stdin
+
+	combined := &syncBuffer{}
+	report := func(stderr bool) *lineWriter {
+		return newLineWriter(func(line string) {
+			reporter.Report(name, &OutputEvent{Time: time.Now(), Step: label, Line: line, Stderr: stderr})
+		})
+	}
+	stdoutLines, stderrLines := report(false), report(true)
+
+	cmd.Stdout = io.MultiWriter(combined, stdoutLines)
+	cmd.Stderr = io.MultiWriter(combined, stderrLines)
+	if m.Output != "" {
+		cmd.Stdout = io.MultiWriter(cmd.Stdout, stdout)
+	}
+
+	switch {
+	case interactive:
+		if cmd.Stdin == nil {
+			cmd.Stdin = os.Stdin
+		}
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		r.Err = cmd.Run()
+
+	case m.TTY:
+		out := io.MultiWriter(combined, stdoutLines)
+		if m.Output != "" {
+			out = io.MultiWriter(out, stdout)
+		}
+
+		r.Err = runInTerminal(cmd, out)
+		if errors.Is(r.Err, errors.ErrUnsupported) {
+			reporter.Report(name, &WarningEvent{
+				Time:    time.Now(),
+				Message: fmt.Sprintf("tty is not supported on %s, running without it", runtime.GOOS),
+			})
+			r.Err = cmd.Run()
+		}
+
+	default:
+		r.Err = cmd.Run()
+	}
+	stdoutLines.Flush()
+	stderrLines.Flush()
+
+	if cmd.ProcessState != nil {
+		r.Usage = processUsage(cmd.ProcessState)
+		if r.Err == nil && m.MaxMemory > 0 && maxMemorySupported && r.Usage.MaxRSS > m.MaxMemory {
+			r.Err = fmt.Errorf("max memory exceeded: %s > %s", r.Usage.MaxRSS, m.MaxMemory)
+		}
+	}
+
+	r.Output = combined.Bytes()
+	r.Duration = time.Since(start)
+	return r
+}
+
+// terminateOnCancel makes command signal its process group once context is cancelled,
+// and kill the process group if it's still running after KillGrace or another signal. The returned function must be called
+// once the command exits, so that the kill timer doesn't signal a process group, which ID was reused.
+//
+// Interactive commands stay in the process group of the caller, so that they can read from the terminal,
+// only the command itself is signalled.
+func (m *CommandModule[State]) terminateOnCancel(ctx context.Context, cmd *exec.Cmd, interactive bool) (stop func()) {
+	grace := m.KillGrace
+	if grace <= 0 {
+		grace = DefaultKillGrace
+	}
+
+	signal := func(sig os.Signal) error { return signalProcessGroup(cmd, sig) }
+	if interactive {
+		signal = func(sig os.Signal) error { return cmd.Process.Signal(sig) }
 	} else {
-		fmt.Printf(
-			"%s %s %s\n",
-			color.GreenString("✓"),
-			GetModuleName(ctx),
-			color.BlackString(duration),
+		setProcessGroup(cmd)
+	}
+
+	var (
+		lock    sync.Mutex
+		kill    *time.Timer
+		stopped bool
+		done    = make(chan struct{})
+	)
+
+	cmd.WaitDelay = grace + time.Second
+	cmd.Cancel = func() error {
+		var (
+			sig    os.Signal = syscall.SIGTERM
+			killed <-chan struct{}
 		)
+		if cause := (*SignalCause)(nil); errors.As(context.Cause(ctx), &cause) {
+			sig, killed = cause.Signal, cause.killed
+		}
+
+		lock.Lock()
+		if !stopped {
+			kill = time.AfterFunc(grace, func() {
+				_ = signal(syscall.SIGKILL)
+			})
+			if killed != nil {
+				// another signal, the user doesn't want to wait for the grace period
+				go func() {
+					select {
+					case <-killed:
+					case <-done:
+						return
+					}
+
+					lock.Lock()
+					defer lock.Unlock()
+					if !stopped {
+						_ = signal(syscall.SIGKILL)
+					}
+				}()
+			}
+		}
+		lock.Unlock()
+
+		if interactive && sig == os.Interrupt {
+			// Ctrl+C is delivered by the terminal to its foreground process group, including the command
+			return nil
+		}
+		return signal(sig)
 	}
 
-	if m.Verbose {
-		color.Black("$ %s", str)
+	return func() {
+		lock.Lock()
+		defer lock.Unlock()
+
+		stopped = true
+		close(done)
+		if kill != nil {
+			kill.Stop()
+		}
+	}
+}
+
+// publishOutputs stores values written to output file along with captured stdout.
+func (m *CommandModule[State]) publishOutputs(ctx context.Context, path string, stdout []byte) error {
+	values := make(map[string]string)
+
+	env, err := ReadEnvFile(path)
+	if err != nil {
+		return fmt.Errorf("reading outputs: %w", err)
+	}
+	for _, kv := range env {
+		k, v, _ := strings.Cut(kv, "=")
+		values[k] = v
 	}
 
+	if m.Output != "" {
+		values[m.Output] = strings.TrimSpace(string(stdout))
+	}
+
+	m.Outputs.Set(GetModuleName(ctx), values)
+	return nil
+}
+
+func createOutputFile() (string, error) {
+	f, err := os.CreateTemp("", "fexec-output-*")
 	if err != nil {
-		if !m.Verbose {
-			color.Black("$ %s", str)
+		return "", fmt.Errorf("creating output file: %w", err)
+	}
+	return f.Name(), f.Close()
+}
+
+// environ builds command environment: inherited variables, then `extra` ones, then env files, then Env.
+func (m *CommandModule[State]) environ(ctx context.Context, dir string, extra []string, reporter Reporter) ([]string, error) {
+	env := os.Environ()
+	if m.CleanEnv {
+		env = filterEnv(env, m.PassEnv)
+	}
+	env = append(env, extra...)
+
+	own, err := m.ownEnviron(ctx, dir, env, reporter)
+	if err != nil {
+		return nil, err
+	}
+	return append(env, own...), nil
+}
+
+// ownEnviron returns variables set by env files and `Env`, expanded on top of `base`.
+func (m *CommandModule[State]) ownEnviron(ctx context.Context, dir string, base []string, reporter Reporter) ([]string, error) {
+	env := slices.Clone(base)
+	for _, path := range m.EnvFiles {
+		path = m.expand(path, env)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
 		}
-		color.Red(err.Error())
-		fmt.Println(string(out))
-	} else if len(out) > 0 {
-		if m.Verbose {
-			fmt.Println()
-			color.Black(string(out))
+
+		vars, err := ReadEnvFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			reporter.Report(GetModuleName(ctx), &WarningEvent{
+				Time:    time.Now(),
+				Message: fmt.Sprintf("env file not found: %s", path),
+			})
+			continue
 		}
+		if err != nil {
+			return nil, fmt.Errorf("reading env file: %w", err)
+		}
+		env = append(env, vars...)
 	}
 
-	if m.Verbose {
-		fmt.Println()
+	for _, kv := range m.Env {
+		env = append(env, m.expand(kv, env))
 	}
 
-	return err
+	return env[len(base):], nil
+}
+
+// lookup returns value of a variable or an output referenced as `module.KEY`.
+func (m *CommandModule[State]) lookup(name string) (string, bool) {
+	if v, ok := m.Vars[name]; ok {
+		return v, true
+	}
+	if i := strings.LastIndex(name, "."); i > 0 && m.Outputs != nil {
+		if m.Outputs.Skipped(name[:i]) {
+			// skipped modules count as satisfied, their outputs are empty
+			return "", true
+		}
+		return m.Outputs.Get(name[:i], name[i+1:])
+	}
+	return "", false
+}
+
+// checkOutputs reports references to outputs of dependencies, that ran, but didn't publish them, e.g. due to a typo.
+// References to outputs of dependencies, that didn't run (e.g. were skipped), are empty and only reported as warnings.
+func (m *CommandModule[State]) checkOutputs(module string, reporter Reporter) error {
+	if m.Outputs == nil {
+		return nil
+	}
+
+	var errs []error
+	seen := make(map[string]bool)
+	check := func(name string) string {
+		i := strings.LastIndex(name, ".")
+		if i <= 0 || seen[name] || !slices.Contains(m.DependsOn, name[:i]) {
+			return ""
+		}
+		seen[name] = true
+
+		dependency, key := name[:i], name[i+1:]
+		if !m.Outputs.Published(dependency) {
+			reporter.Report(module, &WarningEvent{
+				Time:    time.Now(),
+				Message: fmt.Sprintf("output %q of %q is empty, as %q didn't run", key, dependency, dependency),
+			})
+		} else if _, ok := m.Outputs.Get(dependency, key); !ok {
+			errs = append(errs, fmt.Errorf("output %q is not published by %q", key, dependency))
+		}
+		return ""
+	}
+
+	templates := append([]string{m.Dir}, m.EnvFiles...)
+	templates = append(templates, m.Env...)
+	if m.Stdin != nil {
+		templates = append(templates, m.Stdin.File)
+	}
+	for _, step := range m.steps() {
+		templates = append(templates, step.Command...)
+
+		for i := 0; i < len(step.Script); i++ {
+			if step.Script[i] == '$' {
+				name, size := scriptReference(step.Script[i+1:])
+				check(name)
+				i += size
+			}
+		}
+	}
+	for _, s := range templates {
+		os.Expand(s, check)
+	}
+	return errors.Join(errs...)
+}
+
+// expand substitutes variables in `s`: Vars, then outputs, then `env`, then process environment.
+func (m *CommandModule[State]) expand(s string, env []string) string {
+	return os.Expand(s, func(name string) string {
+		if v, ok := m.lookup(name); ok {
+			return v
+		}
+		if v, ok := lookupEnv(env, name); ok {
+			return v
+		}
+		return os.Getenv(name)
+	})
+}
+
+// expandScript substitutes Vars and outputs referenced in `s` as `$NAME` or `${NAME}`.
+// Everything else, e.g. `$1`, `$NF` in awk programs or `$$`, is kept byte-for-byte for the shell.
+func (m *CommandModule[State]) expandScript(s string) string {
+	buf := strings.Builder{}
+	for i := 0; i < len(s); {
+		if s[i] != '$' {
+			buf.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		name, size := scriptReference(s[i+1:])
+		if v, ok := m.lookup(name); ok && name != "" {
+			buf.WriteString(v)
+		} else {
+			buf.WriteString(s[i : i+1+size])
+		}
+		i += 1 + size
+	}
+	return buf.String()
+}
+
+// scriptReference parses a variable reference following `$`: `{NAME}` or `NAME`, where NAME starts with
+// a letter or an underscore. It returns the name (empty if there's no reference) and the number of bytes taken.
+func scriptReference(s string) (string, int) {
+	switch {
+	case s == "":
+		return "", 0
+	case s[0] == '$':
+		// `$$` is a PID in shell, don't treat the second dollar as a start of another reference
+		return "", 1
+	case s[0] == '{':
+		if end := strings.IndexByte(s, '}'); end > 0 {
+			return s[1:end], end + 1
+		}
+		return "", 0
+	}
+
+	size := 0
+	for size < len(s) {
+		c := s[size]
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (size == 0 || c < '0' || c > '9') {
+			break
+		}
+		size++
+	}
+	return s[:size], size
 }
 
 func (m *CommandModule[State]) Dependencies(context.Context) []string {
 	return m.DependsOn
 }
+
+// Validate reports conflicting and ineffective options, all problems are joined into a single error.
+func (m *CommandModule[State]) Validate() error {
+	var errs []error
+
+	switch {
+	case len(m.Steps) > 0 && (len(m.Command) > 0 || m.Script != ""):
+		errs = append(errs, errors.New("steps can't be combined with command or script"))
+	case len(m.Command) > 0 && m.Script != "":
+		errs = append(errs, errors.New("command and script are mutually exclusive"))
+	case len(m.Steps) == 0 && len(m.Command) == 0 && m.Script == "":
+		errs = append(errs, errors.New("nothing to run: command, script or steps must be set"))
+	}
+
+	for i, step := range m.Steps {
+		if (len(step.Command) > 0) == (step.Script != "") {
+			errs = append(errs, fmt.Errorf("step %s: either command or script must be set", step.label(i)))
+		}
+	}
+
+	if len(m.PassEnv) > 0 && !m.CleanEnv {
+		errs = append(errs, errors.New("pass_env has no effect without clean_env"))
+	}
+	if m.KillGrace < 0 {
+		errs = append(errs, errors.New("kill_grace must not be negative"))
+	}
+	if m.MaxMemory < 0 {
+		errs = append(errs, errors.New("max_memory must not be negative"))
+	}
+	if m.Stdin != nil {
+		if err := m.Stdin.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("stdin: %w", err))
+		}
+		if m.TTY {
+			errs = append(errs, errors.New("stdin has no effect with tty"))
+		}
+	}
+	if m.Interactive && m.TTY {
+		errs = append(errs, errors.New("interactive and tty are mutually exclusive"))
+	}
+	if m.Interactive && (m.Output != "" || m.ErrorOnOutput) {
+		errs = append(errs, errors.New("output and error_on_output have no effect in interactive mode, as output is not captured"))
+	}
+	for _, pattern := range m.Mask {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("mask %q: %w", pattern, err))
+		}
+	}
+
+	for _, c := range []struct {
+		name string
+		cond *Condition
+	}{{"when", m.When}, {"skip_if", m.SkipIf}} {
+		if c.cond != nil && c.cond.ChangedBase != "" && len(c.cond.Changed) == 0 {
+			errs = append(errs, fmt.Errorf("%s: changed_base has no effect without changed", c.name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,35 @@
+package framework
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DefaultShell runs scripts, unless `CommandModule.Shell` is set.
+var DefaultShell = []string{"sh", "-eu", "-c"}
+
+// CommandStep is a single command run by `CommandModule`, either an argv list or a shell script.
+type CommandStep struct {
+	Name    string   `yaml:"name" json:"name,omitempty"`
+	Command []string `yaml:"command" json:"command,omitempty"`
+
+	// Script is passed to shell as a single argument.
+	// Only Vars and outputs are substituted in scripts, other references are left to the shell.
+	Script string `yaml:"script" json:"script,omitempty"`
+}
+
+// String returns human-readable representation of the step.
+// Multi-line scripts are indented after the first line.
+func (s CommandStep) String() string {
+	if s.Script != "" {
+		return strings.ReplaceAll(strings.TrimSpace(s.Script), "\n", "\n  ")
+	}
+	return strings.Join(s.Command, " ")
+}
+
+func (s CommandStep) label(i int) string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return strconv.Itoa(i + 1)
+}
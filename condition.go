@@ -0,0 +1,118 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/roboslone/go-framework/v2/internal/git"
+	"github.com/roboslone/go-framework/v2/internal/glob"
+)
+
+// Condition is a set of predicates, it holds if all of its predicates hold.
+// Empty condition always holds.
+type Condition struct {
+	// Env maps names of environment variables to expected values.
+	// Empty value only requires variable to be set.
+	Env map[string]string `yaml:"env"`
+
+	// Files must exist. Relative paths are resolved against directory of the module.
+	Files []string `yaml:"files"`
+
+	// OS and Arch list allowed values of `runtime.GOOS` and `runtime.GOARCH`.
+	OS   []string `yaml:"os"`
+	Arch []string `yaml:"arch"`
+
+	// Probe is a script, that must exit with zero code.
+	Probe string `yaml:"probe"`
+
+	// Changed lists patterns (relative to repository root, `**` is supported),
+	// at least one file changed since ChangedBase must match.
+	Changed []string `yaml:"changed"`
+
+	// ChangedBase is a git revision changes are computed against, defaults to HEAD (uncommitted changes only).
+	ChangedBase string `yaml:"changed_base"`
+}
+
+// Evaluate reports whether condition holds for a command running in `dir` with environment `env`.
+// If condition doesn't hold, the reason is returned.
+func (c *Condition) Evaluate(ctx context.Context, dir string, env []string, shell []string) (bool, string, error) {
+	for _, name := range slices.Sorted(maps.Keys(c.Env)) {
+		value, ok := lookupEnv(env, name)
+		if !ok {
+			return false, fmt.Sprintf("$%s is not set", name), nil
+		}
+		if expected := c.Env[name]; expected != "" && value != expected {
+			return false, fmt.Sprintf("$%s is not %q", name, expected), nil
+		}
+	}
+
+	for _, path := range c.Files {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return false, fmt.Sprintf("%s doesn't exist", path), nil
+		} else if err != nil {
+			return false, "", fmt.Errorf("stat %q: %w", path, err)
+		}
+	}
+
+	if len(c.OS) > 0 && !slices.Contains(c.OS, runtime.GOOS) {
+		return false, fmt.Sprintf("os is %s", runtime.GOOS), nil
+	}
+	if len(c.Arch) > 0 && !slices.Contains(c.Arch, runtime.GOARCH) {
+		return false, fmt.Sprintf("arch is %s", runtime.GOARCH), nil
+	}
+
+	if c.Probe != "" {
+		cmd := exec.CommandContext(ctx, shell[0], append(slices.Clone(shell[1:]), c.Probe)...)
+		cmd.Dir = dir
+		cmd.Env = env
+
+		var exitErr *exec.ExitError
+		if err := cmd.Run(); errors.As(err, &exitErr) {
+			return false, fmt.Sprintf("`%s` exited with code %d", c.Probe, exitErr.ExitCode()), nil
+		} else if err != nil {
+			return false, "", fmt.Errorf("probe %q: %w", c.Probe, err)
+		}
+	}
+
+	if len(c.Changed) > 0 {
+		files, err := git.ChangedFiles(ctx, dir, c.ChangedBase)
+		if err != nil {
+			return false, "", fmt.Errorf("listing changed files: %w", err)
+		}
+
+		var changed bool
+		for _, f := range files {
+			if changed, err = glob.MatchAny(c.Changed, f); err != nil {
+				return false, "", fmt.Errorf("matching changed files: %w", err)
+			}
+			if changed {
+				break
+			}
+		}
+		if !changed {
+			return false, fmt.Sprintf("no changes in %s", strings.Join(c.Changed, ", ")), nil
+		}
+	}
+
+	return true, "", nil
+}
+
+func lookupEnv(env []string, name string) (string, bool) {
+	for i := len(env) - 1; i >= 0; i-- {
+		if v, ok := strings.CutPrefix(env[i], name+"="); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,112 @@
+package framework
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ReadEnvFile parses dotenv file at given path.
+// See `ParseEnv` for supported syntax.
+func ReadEnvFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	env, err := ParseEnv(f)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", path, err)
+	}
+	return env, nil
+}
+
+// ParseEnv parses dotenv content into `KEY=VALUE` pairs.
+//
+// Empty lines and lines starting with `#` are ignored, `export` prefix is allowed.
+// Values can be single-quoted (taken literally) or double-quoted (Go escape sequences are supported).
+// Unquoted values are trimmed, trailing ` #` comments are removed.
+func ParseEnv(r io.Reader) ([]string, error) {
+	var env []string
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", n)
+		}
+
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(value, `"`), strings.HasPrefix(value, "'"):
+			end := closingQuote(value)
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quote", n)
+			}
+			if rest := strings.TrimSpace(value[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+				return nil, fmt.Errorf("line %d: unexpected characters after quoted value", n)
+			}
+
+			if value[0] == '\'' {
+				value = value[1:end]
+				break
+			}
+
+			unquoted, err := strconv.Unquote(value[:end+1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			value = unquoted
+
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+
+		env = append(env, key+"="+value)
+	}
+
+	return env, scanner.Err()
+}
+
+// filterEnv returns variables from `env` which names match any of `patterns`.
+// Patterns are matched using `filepath.Match`.
+func filterEnv(env []string, patterns []string) []string {
+	var result []string
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(pattern, name); ok {
+				result = append(result, kv)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// closingQuote returns index of the quote closing the one `s` starts with, or -1.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && s[0] == '"':
+			i++
+		case s[i] == s[0]:
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,98 @@
+package fexec
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/roboslone/go-framework/v2"
+	"github.com/roboslone/go-framework/v2/internal/git"
+	"github.com/roboslone/go-framework/v2/internal/glob"
+)
+
+// ChangedFiles returns absolute paths of files changed since `base` in git repository containing `dir`,
+// see `git.ChangedFiles`.
+func ChangedFiles(ctx context.Context, dir, base string) ([]string, error) {
+	root, err := git.Root(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := git.ChangedFiles(ctx, dir, base)
+	if err != nil {
+		return nil, err
+	}
+	for i, f := range files {
+		files[i] = filepath.Join(root, filepath.FromSlash(f))
+	}
+	return files, nil
+}
+
+// Affected returns modules of the topology affected by changes of given files (absolute paths), in topological order.
+//
+// Command is affected if any of the files matches its sources (or is located within its dir, if sources are not set).
+// Any module (including those without commands) is affected if any of its dependencies is affected.
+func Affected[State any](
+	cfg *CommandConfig,
+	modules framework.Modules,
+	topology *framework.Topology,
+	files []string,
+) ([]string, error) {
+	direct := make(map[string]bool)
+	for _, name := range topology.OrderedModuleNames {
+		module, ok := modules[name].(*framework.CommandModule[State])
+		if !ok {
+			continue
+		}
+
+		affected, err := matchSources(module.Dir, cfg.Commands[name].Sources, files)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", name, err)
+		}
+		direct[name] = affected
+	}
+
+	var result []string
+	for _, name := range topology.OrderedModuleNames {
+		if direct[name] || slices.ContainsFunc(topology.FullDependencies[name], func(d string) bool { return direct[d] }) {
+			result = append(result, name)
+		}
+	}
+	return result, nil
+}
+
+func matchSources(dir string, sources []string, files []string) (bool, error) {
+	// changed files are located using path of repository root, which has symlinks resolved
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+
+	for _, f := range files {
+		rel, err := filepath.Rel(dir, f)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		outside := rel == ".." || strings.HasPrefix(rel, "../")
+
+		if len(sources) == 0 {
+			if !outside {
+				return true, nil
+			}
+			continue
+		}
+
+		for _, pattern := range sources {
+			// files outside of dir are only matched by patterns explicitly pointing there, not by `**`
+			if outside && !strings.HasPrefix(pattern, "../") {
+				continue
+			}
+			if ok, err := glob.Match(pattern, rel); ok || err != nil {
+				return ok, err
+			}
+		}
+	}
+	return false, nil
+}
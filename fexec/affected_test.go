@@ -0,0 +1,61 @@
+package fexec_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	framework "github.com/roboslone/go-framework/v2"
+	"github.com/roboslone/go-framework/v2/fexec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAffected(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".fexec.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+commands:
+  lib: {script: "true", dir: lib}
+  a: {script: "true", dir: svc/a, dependencies: [lib]}
+  b: {script: "true", dir: svc/b, sources: ["**/*.go", "../../proto/**"]}
+  ci: {dependencies: [a, b]}
+`), 0o644))
+
+	cfg, err := fexec.ParseConfig(path)
+	require.NoError(t, err)
+	modules := fexec.BuildModules[State](cfg, dir, nil, nil)
+	topology, err := framework.NewApplication[State](t.Name(), modules).BuildTopology(t.Context(), "ci")
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		files    []string
+		affected []string
+	}{
+		{nil, nil},
+		{[]string{"README.md", "svc/b/README.md"}, nil},
+		{[]string{"svc/b/cmd/main.go"}, []string{"b", "ci"}},
+		{[]string{"proto/api.proto"}, []string{"b", "ci"}},
+		{[]string{"svc/a/main.go"}, []string{"a", "ci"}},
+		{[]string{"lib/lib.go"}, []string{"lib", "a", "ci"}},
+	} {
+		files := make([]string, 0, len(tc.files))
+		for _, f := range tc.files {
+			files = append(files, filepath.Join(dir, f))
+		}
+
+		affected, err := fexec.Affected[State](cfg, modules, topology, files)
+		require.NoError(t, err)
+		require.Equal(t, tc.affected, affected, "%v", tc.files)
+	}
+
+	// modules outside of the dependency graph of requested ones are not affected
+	topology, err = framework.NewApplication[State](t.Name(), modules).BuildTopology(t.Context(), "a")
+	require.NoError(t, err)
+
+	affected, err := fexec.Affected[State](cfg, modules, topology, []string{
+		filepath.Join(dir, "svc/b/cmd/main.go"),
+		filepath.Join(dir, "lib/lib.go"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"lib", "a"}, affected)
+}
@@ -0,0 +1,119 @@
+package fexec
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/roboslone/go-framework/v2"
+)
+
+// Command is a config entry describing a single `framework.CommandModule`.
+type Command struct {
+	framework.CommandModule[any] `yaml:",inline"`
+
+	// Matrix expands the command into a module per combination of values, e.g. `build[amd64]`.
+	// Values are available as both env and vars. Original name depends on all expanded modules.
+	Matrix map[string][]string `yaml:"matrix"`
+
+	// Sources are patterns of files (relative to dir, `**` is supported) the command depends on,
+	// see `Affected`. Defaults to all files within dir.
+	Sources []string `yaml:"sources"`
+}
+
+// IsNoop reports whether command doesn't run anything and only groups its dependencies.
+func (c Command) IsNoop() bool {
+	return len(c.Command) == 0 && c.Script == "" && len(c.Steps) == 0
+}
+
+// BuildModules builds a module for every command, commands without anything to run become `framework.NoopModule`.
+// Command vars are expanded using `vars`, unless overridden. Relative dirs are resolved against `wd`.
+func BuildModules[State any](cfg *CommandConfig, wd string, vars, overrides map[string]string) framework.Modules {
+	outputs := framework.NewOutputs()
+	modules := framework.Modules{}
+	for name, command := range cfg.Commands {
+		if command.IsNoop() {
+			modules[name] = &framework.NoopModule{DependsOn: command.DependsOn}
+			continue
+		}
+
+		module := framework.CommandModule[State](command.CommandModule)
+
+		moduleVars := maps.Clone(vars)
+		for k, v := range module.Vars {
+			if _, ok := overrides[k]; !ok {
+				moduleVars[k] = ExpandVars(v, vars)
+			}
+		}
+		module.Dir = ExpandVars(module.Dir, moduleVars)
+
+		if module.Dir == "" {
+			module.Dir = wd
+		} else if !filepath.IsAbs(module.Dir) {
+			module.Dir = filepath.Join(wd, module.Dir)
+		}
+
+		module.Vars = moduleVars
+		module.Outputs = outputs
+
+		modules[name] = &module
+	}
+	return modules
+}
+
+// expandMatrix replaces commands with matrix by their expansions.
+func (cfg *CommandConfig) expandMatrix() error {
+	for _, name := range slices.Sorted(maps.Keys(cfg.Commands)) {
+		parent := cfg.Commands[name]
+		if len(parent.Matrix) == 0 {
+			continue
+		}
+
+		keys := slices.Sorted(maps.Keys(parent.Matrix))
+		combinations := [][]string{{}}
+		for _, k := range keys {
+			if len(parent.Matrix[k]) == 0 {
+				return fmt.Errorf("%q: matrix: no values for %q", name, k)
+			}
+
+			next := make([][]string, 0, len(combinations)*len(parent.Matrix[k]))
+			for _, c := range combinations {
+				for _, v := range parent.Matrix[k] {
+					next = append(next, append(slices.Clone(c), v))
+				}
+			}
+			combinations = next
+		}
+
+		expanded := make([]string, 0, len(combinations))
+		for _, values := range combinations {
+			child := parent
+			child.Matrix = nil
+			child.Vars = maps.Clone(parent.Vars)
+			if child.Vars == nil {
+				child.Vars = make(map[string]string, len(keys))
+			}
+			child.Env = slices.Clone(parent.Env)
+
+			for i, k := range keys {
+				child.Vars[k] = values[i]
+				child.Env = append(child.Env, k+"="+values[i])
+			}
+
+			childName := fmt.Sprintf("%s[%s]", name, strings.Join(values, ","))
+			if _, ok := cfg.Commands[childName]; ok {
+				return fmt.Errorf("%q: matrix: command already defined: %q", name, childName)
+			}
+			cfg.Commands[childName] = child
+			expanded = append(expanded, childName)
+		}
+
+		cfg.Commands[name] = Command{
+			CommandModule: framework.CommandModule[any]{DependsOn: expanded},
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,412 @@
+// Package fexec reads configs of the fexec command line tool and builds command modules out of them.
+package fexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/roboslone/go-framework/v2"
+)
+
+const (
+	discoverMaxDepth = 7
+
+	// namespaceSeparator joins the namespace of an included config with its command names,
+	// e.g. `backend:test`.
+	namespaceSeparator = ":"
+)
+
+var (
+	// discoverNames are config file names in order of precedence, see `decodeConfig` for supported formats.
+	discoverNames = []string{
+		".fexec.yaml",
+		".fexec.yml",
+		".fexec.toml",
+		".fexec.json",
+		"package.json",
+		"pyproject.toml",
+	}
+)
+
+// CommandConfig is a config of fexec: a set of commands along with their shared settings.
+type CommandConfig struct {
+	// Include maps namespaces to other configs (or directories containing one).
+	// Included commands are available as `<namespace>:<command>`.
+	Include map[string]string `yaml:"include"`
+
+	// Import lists other configs (or directories containing one),
+	// which commands are merged into this config as is.
+	Import []string `yaml:"import"`
+
+	// Vars are available to all commands, see `Var`.
+	// Vars of included and imported configs are merged, unless already defined.
+	Vars map[string]Var `yaml:"vars"`
+
+	// Env, EnvFiles, CleanEnv and PassEnv are defaults for every command of this config,
+	// see `framework.CommandModule`. Relative env files are resolved against location of the config.
+	Env      []string `yaml:"env"`
+	EnvFiles []string `yaml:"env_file"`
+	CleanEnv bool     `yaml:"clean_env"`
+	PassEnv  []string `yaml:"pass_env"`
+
+	// Secrets and Mask are added to those of every command of this config, see `framework.CommandModule`.
+	Secrets []string `yaml:"secrets"`
+	Mask    []string `yaml:"mask"`
+
+	// Shell runs scripts of commands, that don't define their own, see `framework.CommandModule`.
+	Shell []string `yaml:"shell"`
+
+	// History enables recording of runs in `HistoryDir` next to the config, see `HistoryRecorder`.
+	// Recording queries git for the current commit and uncommitted changes on every run.
+	History bool `yaml:"history"`
+
+	// Notify is sent once a run finishes, see `NotifyConfig`. Notify of included and imported configs is ignored.
+	Notify NotifyConfig `yaml:"notify"`
+
+	Commands map[string]Command `yaml:"commands"`
+}
+
+// LoadConfig reads config at given path (or discovered in given directory) and builds a module for every command,
+// see `BuildModules`. Relative command dirs are resolved against location of the config.
+func LoadConfig[State any](ctx context.Context, path string, overrides map[string]string) (framework.Modules, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		if path, err = findConfig(path); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg, err := ParseConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	vars, err := ResolveVars(ctx, cfg.Vars, overrides)
+	if err != nil {
+		return nil, fmt.Errorf("resolving variables: %w", err)
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("abs %q: %w", path, err)
+	}
+	return BuildModules[State](cfg, dir, vars, overrides), nil
+}
+
+// ParseConfig reads config at given path, along with all included and imported configs.
+//
+// Relative `dir` of included and imported commands is resolved against location of their own config.
+// Commands with matrix are expanded, see `Command`.
+// Glob patterns in dependencies (e.g. `*:test`) are expanded to matching command names.
+func ParseConfig(path string) (*CommandConfig, error) {
+	cfg, err := parseConfig(path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = cfg.expandMatrix(); err != nil {
+		return nil, err
+	}
+
+	if err = cfg.expandDependencies(); err != nil {
+		return nil, fmt.Errorf("dependencies: %w", err)
+	}
+
+	if err = cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("validate:\n%w", err)
+	}
+
+	return cfg, nil
+}
+
+// Validate reports unknown dependencies, dependency cycles, commands without anything to run
+// and conflicting options. All problems are joined into a single error.
+func (cfg *CommandConfig) Validate() error {
+	var errs []error
+
+	for _, name := range slices.Sorted(maps.Keys(cfg.Vars)) {
+		if v := cfg.Vars[name]; v.Value != "" && v.Sh != "" {
+			errs = append(errs, fmt.Errorf("var %q: value and sh are mutually exclusive", name))
+		}
+	}
+
+	if err := cfg.Notify.Validate(); err != nil {
+		errs = append(errs, unjoin(err)...)
+	}
+
+	modules := make(framework.Modules, len(cfg.Commands))
+	for _, name := range slices.Sorted(maps.Keys(cfg.Commands)) {
+		c := cfg.Commands[name]
+		modules[name] = &framework.NoopModule{DependsOn: c.DependsOn}
+
+		for _, d := range c.DependsOn {
+			if _, ok := cfg.Commands[d]; !ok {
+				errs = append(errs, fmt.Errorf("%q: unknown dependency: %q", name, d))
+			}
+		}
+
+		if c.IsNoop() {
+			if len(c.DependsOn) == 0 {
+				errs = append(errs, fmt.Errorf("%q: nothing to run: command, script, steps or dependencies must be set", name))
+			}
+			if len(c.Sources) > 0 {
+				errs = append(errs, fmt.Errorf("%q: sources have no effect without command, script or steps", name))
+			}
+			continue
+		}
+		if err := c.CommandModule.Validate(); err != nil {
+			for _, e := range unjoin(err) {
+				errs = append(errs, fmt.Errorf("%q: %w", name, e))
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		app := framework.NewApplication[any]("fexec", modules)
+		if _, err := app.BuildTopology(context.Background(), slices.Sorted(maps.Keys(modules))...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// unjoin returns errors joined by `errors.Join`, or the error itself.
+func unjoin(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+func parseConfig(path string, stack []string) (*CommandConfig, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("abs %q: %w", path, err)
+	}
+	if slices.Contains(stack, abs) {
+		return nil, fmt.Errorf("include cycle: %s", strings.Join(append(stack, abs), " -> "))
+	}
+	stack = append(stack, abs)
+
+	cfg, err := decodeConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Commands == nil {
+		cfg.Commands = make(map[string]Command)
+	}
+	if cfg.Vars == nil {
+		cfg.Vars = make(map[string]Var)
+	}
+
+	dir := filepath.Dir(path)
+	for name, v := range cfg.Vars {
+		if v.dir == "" {
+			v.dir = dir
+			cfg.Vars[name] = v
+		}
+	}
+	cfg.applyDefaults(dir)
+
+	for _, ref := range cfg.Import {
+		imported, err := parseReference(dir, ref, stack)
+		if err != nil {
+			return nil, fmt.Errorf("import %q: %w", ref, err)
+		}
+
+		for name, module := range imported.Commands {
+			if _, ok := cfg.Commands[name]; ok {
+				return nil, fmt.Errorf("import %q: command already defined: %q", ref, name)
+			}
+			cfg.Commands[name] = module
+		}
+		cfg.mergeVars(imported)
+	}
+
+	for _, namespace := range slices.Sorted(maps.Keys(cfg.Include)) {
+		ref := cfg.Include[namespace]
+
+		if namespace == "" || strings.ContainsAny(namespace, `*?[\`) {
+			return nil, fmt.Errorf("include %q: invalid namespace: %q", ref, namespace)
+		}
+
+		included, err := parseReference(dir, ref, stack)
+		if err != nil {
+			return nil, fmt.Errorf("include %q: %w", ref, err)
+		}
+
+		for name, module := range included.Commands {
+			name = namespace + namespaceSeparator + name
+			if _, ok := cfg.Commands[name]; ok {
+				return nil, fmt.Errorf("include %q: command already defined: %q", ref, name)
+			}
+
+			// a new slice, as the module is a copy sharing dependencies with the included config
+			deps := make([]string, 0, len(module.DependsOn))
+			for _, d := range module.DependsOn {
+				deps = append(deps, namespace+namespaceSeparator+d)
+			}
+			module.DependsOn = deps
+			cfg.Commands[name] = module
+		}
+		cfg.mergeVars(included)
+	}
+
+	return cfg, nil
+}
+
+// applyDefaults propagates top-level environment settings to commands.
+func (cfg *CommandConfig) applyDefaults(dir string) {
+	envFiles := make([]string, 0, len(cfg.EnvFiles))
+	for _, path := range cfg.EnvFiles {
+		if !filepath.IsAbs(path) && !strings.HasPrefix(path, "$") {
+			path = filepath.Join(dir, path)
+		}
+		envFiles = append(envFiles, path)
+	}
+
+	for name, module := range cfg.Commands {
+		module.Env = slices.Concat(cfg.Env, module.Env)
+		module.EnvFiles = slices.Concat(envFiles, module.EnvFiles)
+		module.CleanEnv = module.CleanEnv || cfg.CleanEnv
+		module.PassEnv = slices.Concat(cfg.PassEnv, module.PassEnv)
+		module.Secrets = slices.Concat(cfg.Secrets, module.Secrets)
+		module.Mask = slices.Concat(cfg.Mask, module.Mask)
+		if len(module.Shell) == 0 {
+			module.Shell = cfg.Shell
+		}
+		cfg.Commands[name] = module
+	}
+}
+
+func (cfg *CommandConfig) mergeVars(other *CommandConfig) {
+	for name, v := range other.Vars {
+		if _, ok := cfg.Vars[name]; !ok {
+			cfg.Vars[name] = v
+		}
+	}
+}
+
+// parseReference parses config referenced from another config, located in `dir`.
+// Reference can point to either a config file, or a directory containing one.
+// Command directories of referenced config are resolved against its location.
+func parseReference(dir, ref string, stack []string) (*CommandConfig, error) {
+	path := ref
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stat: %w", err)
+	}
+	if info.IsDir() {
+		if path, err = findConfig(path); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg, err := parseConfig(path, stack)
+	if err != nil {
+		return nil, err
+	}
+
+	refDir := filepath.Dir(path)
+	for name, module := range cfg.Commands {
+		if module.Dir == "" {
+			module.Dir = refDir
+		} else if !filepath.IsAbs(module.Dir) {
+			module.Dir = filepath.Join(refDir, module.Dir)
+		}
+		cfg.Commands[name] = module
+	}
+
+	return cfg, nil
+}
+
+// expandDependencies replaces dependency patterns with names of matching commands.
+// Patterns are matched using `filepath.Match`, exact names are kept as is.
+func (cfg *CommandConfig) expandDependencies() error {
+	for name, module := range cfg.Commands {
+		deps := make([]string, 0, len(module.DependsOn))
+
+		for _, pattern := range module.DependsOn {
+			if _, ok := cfg.Commands[pattern]; ok || !strings.ContainsAny(pattern, `*?[\`) {
+				deps = append(deps, pattern)
+				continue
+			}
+
+			var matched bool
+			for _, candidate := range slices.Sorted(maps.Keys(cfg.Commands)) {
+				match, err := filepath.Match(pattern, candidate)
+				if err != nil {
+					return fmt.Errorf("%q: invalid pattern %q: %w", name, pattern, err)
+				}
+				if match && candidate != name && !slices.Contains(deps, candidate) {
+					deps = append(deps, candidate)
+					matched = true
+				}
+			}
+
+			if !matched {
+				return fmt.Errorf("%q: pattern didn't match any commands: %q", name, pattern)
+			}
+		}
+
+		module.DependsOn = deps
+		cfg.Commands[name] = module
+	}
+
+	return nil
+}
+
+func DiscoverConfigPath() (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("getwd: %w", err)
+	}
+
+	for depth := 0; depth < discoverMaxDepth; depth++ {
+		path, err := findConfig(wd)
+		if err == nil {
+			return path, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		wd = filepath.Dir(wd)
+	}
+
+	return "", fmt.Errorf("file not found (searched for %s)", strings.Join(discoverNames, ", "))
+}
+
+// findConfig returns path to config file located directly in `dir`.
+func findConfig(dir string) (string, error) {
+	for _, name := range discoverNames {
+		path := filepath.Join(dir, name)
+
+		_, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("stat %q: %w", path, err)
+		}
+
+		// project files are only used if they contain config
+		if _, ok := embeddedSections[name]; ok {
+			if _, err = readConfigNode(path); errors.Is(err, errNoEmbeddedConfig) {
+				continue
+			}
+		}
+		return path, nil
+	}
+
+	return "", fmt.Errorf("%w: no config in %q (searched for %s)", os.ErrNotExist, dir, strings.Join(discoverNames, ", "))
+}
@@ -0,0 +1,253 @@
+package fexec_test
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	framework "github.com/roboslone/go-framework/v2"
+	"github.com/roboslone/go-framework/v2/fexec"
+	"github.com/stretchr/testify/require"
+)
+
+type State struct{}
+
+func TestLoadConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		content string
+		value   string
+	}{
+		{".fexec.yaml", "vars: {V: yaml}\ncommands:\n  write: {script: 'echo ${V} > out.txt'}\n", "yaml"},
+		{".fexec.json", `{"vars": {"V": "json"}, "commands": {"write": {"script": "echo ${V} > out.txt"}}}`, "json"},
+		{".fexec.toml", "[vars]\nV = 'toml'\n[commands.write]\nscript = 'echo ${V} > out.txt'\n", "toml"},
+		{"package.json", `{"name": "app", "fexec": {"vars": {"V": "package"}, "commands": {"write": {"script": "echo ${V} > out.txt"}}}}`, "package"},
+		{"pyproject.toml", "[project]\nname = 'app'\n[tool.fexec.vars]\nV = 'pyproject'\n[tool.fexec.commands.write]\nscript = 'echo ${V} > out.txt'\n", "pyproject"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, tc.name), []byte(tc.content), 0o644))
+
+			modules, err := fexec.LoadConfig[State](t.Context(), dir, map[string]string{})
+			require.NoError(t, err)
+
+			app := framework.NewApplication[State](t.Name(), modules)
+			require.NoError(t, app.Run(t.Context(), t.Context(), &State{}, "write"))
+
+			content, err := os.ReadFile(filepath.Join(dir, "out.txt"))
+			require.NoError(t, err)
+			require.Equal(t, tc.value+"\n", string(content))
+		})
+	}
+}
+
+func TestParseConfig_Strict(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".fexec.yaml")
+
+	require.NoError(t, os.WriteFile(path, []byte("commands:\n  a:\n    command: [echo]\n    dependecies: [b]\n"), 0o644))
+	_, err := fexec.ParseConfig(path)
+	require.ErrorContains(t, err, `line 4: unknown field "dependecies" in commands.a`)
+
+	require.NoError(t, os.WriteFile(path, []byte("x-defaults: &d {env: [A=1]}\ncommands:\n  a: {<<: *d, command: [echo], dependencies: [b]}\n"), 0o644))
+	_, err = fexec.ParseConfig(path)
+	require.ErrorContains(t, err, `"a": unknown dependency: "b"`)
+}
+
+// writeConfigs writes configs to given paths within `dir`.
+func writeConfigs(t *testing.T, dir string, configs map[string]string) {
+	t.Helper()
+
+	for path, content := range configs {
+		path = filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func TestParseConfig_Matrix(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigs(t, dir, map[string]string{".fexec.yaml": `
+commands:
+  build:
+    script: 'echo "$os/${arch} ${V} $CGO_ENABLED" >> out.txt'
+    env: [CGO_ENABLED=0]
+    vars: {V: "1"}
+    matrix: {os: [linux, darwin], arch: [arm64]}
+  release: {script: "true", dependencies: [build]}
+  smoke: {script: "true", dependencies: ["build[arm64,linux]"]}
+`})
+
+	cfg, err := fexec.ParseConfig(filepath.Join(dir, ".fexec.yaml"))
+	require.NoError(t, err)
+
+	// values are ordered by key, combinations follow the order of values
+	require.ElementsMatch(t, []string{"build", "build[arm64,darwin]", "build[arm64,linux]", "release", "smoke"}, slices.Collect(maps.Keys(cfg.Commands)))
+	require.True(t, cfg.Commands["build"].IsNoop())
+	require.Equal(t, []string{"build[arm64,linux]", "build[arm64,darwin]"}, cfg.Commands["build"].DependsOn)
+
+	linux := cfg.Commands["build[arm64,linux]"]
+	require.Nil(t, linux.Matrix)
+	require.Equal(t, map[string]string{"V": "1", "arch": "arm64", "os": "linux"}, linux.Vars)
+	require.Equal(t, []string{"CGO_ENABLED=0", "arch=arm64", "os=linux"}, linux.Env)
+	require.Equal(t, map[string]string{"V": "1", "arch": "arm64", "os": "darwin"}, cfg.Commands["build[arm64,darwin]"].Vars, "expansions don't share vars")
+
+	modules, err := fexec.LoadConfig[State](t.Context(), dir, nil)
+	require.NoError(t, err)
+
+	app := framework.NewApplication[State](t.Name(), modules)
+	topology, err := app.BuildTopology(t.Context(), "release", "smoke")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"build[arm64,darwin]", "build[arm64,linux]", "build", "release", "smoke"}, topology.OrderedModuleNames)
+	require.Equal(t, []string{"build[arm64,linux]"}, topology.DirectDependencies["smoke"])
+
+	require.NoError(t, app.Run(t.Context(), t.Context(), &State{}, "smoke"))
+	content, err := os.ReadFile(filepath.Join(dir, "out.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "linux/arm64 1 0\n", string(content))
+
+	for content, expected := range map[string]string{
+		"commands:\n  build: {script: 'true', matrix: {os: []}}\n":                                `"build": matrix: no values for "os"`,
+		"commands:\n  build: {script: 'true', matrix: {os: [a]}}\n  build[a]: {script: 'true'}\n": `"build": matrix: command already defined: "build[a]"`,
+	} {
+		writeConfigs(t, dir, map[string]string{".fexec.yaml": content})
+		_, err = fexec.ParseConfig(filepath.Join(dir, ".fexec.yaml"))
+		require.ErrorContains(t, err, expected)
+	}
+}
+
+func TestParseConfig_Include(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigs(t, dir, map[string]string{
+		".fexec.yaml": `
+include:
+  backend: services/backend
+  web: services/web/web.yaml
+import: [tools]
+vars: {VERSION: root}
+commands:
+  ci: {dependencies: ["*:test"]}
+`,
+		"services/backend/.fexec.yaml": `
+commands:
+  build: {command: [go, build]}
+  test: {command: [go, test], dir: cmd, dependencies: [build]}
+`,
+		"services/web/web.yaml": `
+vars: {VERSION: web, NODE: "20"}
+commands:
+  test: {command: [npm, test], dir: /abs}
+`,
+		"tools/.fexec.yaml": `
+commands:
+  lint: {command: [lint], dir: ..}
+`,
+	})
+
+	cfg, err := fexec.ParseConfig(filepath.Join(dir, ".fexec.yaml"))
+	require.NoError(t, err)
+
+	require.ElementsMatch(t, []string{"backend:build", "backend:test", "web:test", "lint", "ci"}, slices.Collect(maps.Keys(cfg.Commands)))
+	for name, expected := range map[string]struct {
+		dir  string
+		deps []string
+	}{
+		"backend:build": {filepath.Join(dir, "services/backend"), []string{}},
+		"backend:test":  {filepath.Join(dir, "services/backend/cmd"), []string{"backend:build"}},
+		"web:test":      {"/abs", []string{}},
+		"lint":          {dir, []string{}},
+		"ci":            {"", []string{"backend:test", "web:test"}},
+	} {
+		require.Equal(t, expected.dir, cfg.Commands[name].Dir, name)
+		require.Equal(t, expected.deps, cfg.Commands[name].DependsOn, name)
+	}
+
+	// vars of the including config take precedence
+	require.Equal(t, "root", cfg.Vars["VERSION"].Value)
+	require.Equal(t, "20", cfg.Vars["NODE"].Value)
+}
+
+func TestParseConfig_IncludeTwice(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigs(t, dir, map[string]string{
+		".fexec.yaml":     "include: {a: lib, b: lib}\n",
+		"lib/.fexec.yaml": "commands:\n  build: {command: [make]}\n  test: {command: [make, test], dependencies: [build]}\n",
+	})
+
+	cfg, err := fexec.ParseConfig(filepath.Join(dir, ".fexec.yaml"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"a:build"}, cfg.Commands["a:test"].DependsOn)
+	require.Equal(t, []string{"b:build"}, cfg.Commands["b:test"].DependsOn)
+}
+
+func TestParseConfig_IncludeErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		configs map[string]string
+		err     string
+	}{
+		{
+			name: "cycle",
+			configs: map[string]string{
+				".fexec.yaml":   "include: {a: a}\ncommands:\n  x: {command: [x]}\n",
+				"a/.fexec.yaml": "include: {root: ..}\n",
+			},
+			err: "include cycle: ",
+		},
+		{
+			name: "import cycle",
+			configs: map[string]string{
+				".fexec.yaml": "import: [.fexec.yaml]\n",
+			},
+			err: "include cycle: ",
+		},
+		{
+			name: "import duplicates local command",
+			configs: map[string]string{
+				".fexec.yaml":       "import: [tools]\ncommands:\n  lint: {command: [lint]}\n",
+				"tools/.fexec.yaml": "commands:\n  lint: {command: [other-lint]}\n",
+			},
+			err: `import "tools": command already defined: "lint"`,
+		},
+		{
+			name: "include duplicates local command",
+			configs: map[string]string{
+				".fexec.yaml":     "include: {lib: lib}\ncommands:\n  lib:test: {command: [test]}\n",
+				"lib/.fexec.yaml": "commands:\n  test: {command: [test]}\n",
+			},
+			err: `include "lib": command already defined: "lib:test"`,
+		},
+		{
+			name: "invalid namespace",
+			configs: map[string]string{
+				".fexec.yaml":     "include: {'lib*': lib}\n",
+				"lib/.fexec.yaml": "commands:\n  test: {command: [test]}\n",
+			},
+			err: `invalid namespace: "lib*"`,
+		},
+		{
+			name: "missing config",
+			configs: map[string]string{
+				".fexec.yaml": "include: {lib: lib}\n",
+			},
+			err: `include "lib": stat: `,
+		},
+		{
+			name: "pattern without matches",
+			configs: map[string]string{
+				".fexec.yaml": "commands:\n  ci: {dependencies: ['*:test']}\n",
+			},
+			err: `"ci": pattern didn't match any commands: "*:test"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeConfigs(t, dir, tc.configs)
+
+			_, err := fexec.ParseConfig(filepath.Join(dir, ".fexec.yaml"))
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package fexec
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// embeddedSections map names of project files to the location of config within them.
+	embeddedSections = map[string][]string{
+		"package.json":   {"fexec"},
+		"pyproject.toml": {"tool", "fexec"},
+	}
+
+	errNoEmbeddedConfig = errors.New("no embedded config")
+)
+
+// extensionPrefix marks keys ignored by decoding, e.g. `x-defaults: &defaults` holding YAML anchors.
+const extensionPrefix = "x-"
+
+// decodeConfig reads config from YAML, JSON or TOML file, format is chosen by extension.
+// Config can also be embedded into a project file, see `embeddedSections`.
+//
+// Unknown fields are reported along with their line numbers (TOML doesn't provide them, only key paths are reported).
+func decodeConfig(path string) (*CommandConfig, error) {
+	node, err := readConfigNode(path)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	cfg := &CommandConfig{}
+	if node == nil {
+		return cfg, nil
+	}
+	if errs := checkFields(reflect.TypeFor[CommandConfig](), node, ""); len(errs) > 0 {
+		return nil, fmt.Errorf("parse %s: %w", path, errors.Join(errs...))
+	}
+	if err = node.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// readConfigNode returns a node holding the config, nil for empty documents.
+func readConfigNode(path string) (*yaml.Node, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+
+	node := &yaml.Node{}
+	if filepath.Ext(path) == ".toml" {
+		var values map[string]any
+		if _, err = toml.Decode(string(content), &values); err != nil {
+			return nil, err
+		}
+		if err = node.Encode(values); err != nil {
+			return nil, err
+		}
+	} else if err = yaml.Unmarshal(content, node); err != nil {
+		// JSON is a subset of YAML
+		return nil, err
+	}
+
+	if node.Kind == yaml.DocumentNode {
+		node = node.Content[0]
+	}
+
+	if section, ok := embeddedSections[filepath.Base(path)]; ok {
+		for _, key := range section {
+			if node = mappingValue(node, key); node == nil {
+				return nil, fmt.Errorf("%w: no %q section", errNoEmbeddedConfig, strings.Join(section, "."))
+			}
+		}
+	}
+
+	if node.Kind == 0 {
+		return nil, nil
+	}
+	return node, nil
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// checkFields reports mapping keys, that don't match any field of `t`.
+// Mismatching kinds are ignored, as those are reported by decoding.
+func checkFields(t reflect.Type, node *yaml.Node, path string) []error {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	var errs []error
+	switch t.Kind() {
+	case reflect.Pointer:
+		return checkFields(t.Elem(), node, path)
+
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return nil
+		}
+		for i, item := range node.Content {
+			errs = append(errs, checkFields(t.Elem(), item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			errs = append(errs, checkFields(t.Elem(), node.Content[i+1], joinPath(path, node.Content[i].Value))...)
+		}
+
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+
+			// merge key, e.g. `<<: *defaults`
+			if key.Tag == "!!merge" {
+				merged := []*yaml.Node{value}
+				if value.Kind == yaml.SequenceNode {
+					merged = value.Content
+				}
+				for _, m := range merged {
+					errs = append(errs, checkFields(t, m, path)...)
+				}
+				continue
+			}
+
+			if strings.HasPrefix(key.Value, extensionPrefix) {
+				continue
+			}
+
+			field, ok := fields[key.Value]
+			if !ok {
+				errs = append(errs, unknownField(key, path))
+				continue
+			}
+			errs = append(errs, checkFields(field, value, joinPath(path, key.Value))...)
+		}
+	}
+	return errs
+}
+
+func unknownField(key *yaml.Node, path string) error {
+	var location string
+	if key.Line > 0 {
+		location = fmt.Sprintf("line %d: ", key.Line)
+	}
+	if path != "" {
+		return fmt.Errorf("%sunknown field %q in %s", location, key.Value, path)
+	}
+	return fmt.Errorf("%sunknown field %q", location, key.Value)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// yamlFields maps yaml names of struct fields to their types, fields of inline structs are included.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		switch {
+		case name == "-":
+			continue
+		case opts == "inline":
+			maps.Copy(fields, yamlFields(f.Type))
+			continue
+		case name == "":
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
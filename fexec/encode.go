@@ -0,0 +1,121 @@
+package fexec
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/roboslone/go-framework/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// EncodeConfig returns YAML of the config. Empty fields are omitted, vars without `sh` are written as plain values.
+func EncodeConfig(cfg *CommandConfig) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(4)
+	if err := enc.Encode(encodeNode(reflect.ValueOf(cfg))); err != nil {
+		return nil, fmt.Errorf("encoding config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("encoding config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeNode(v reflect.Value) *yaml.Node {
+	switch v.Type() {
+	case reflect.TypeFor[Var]():
+		if v := v.Interface().(Var); v.Sh == "" {
+			return encodeNode(reflect.ValueOf(v.Value))
+		}
+	case inputType:
+		switch input := v.Interface().(framework.Input); {
+		case input == framework.Input{Inherit: true}:
+			return scalarNode("inherit")
+		case input.File == "" && !input.Inherit && input.Text != "inherit":
+			// `inherit` scalar stands for the process stdin, so such text is kept in a mapping
+			return encodeNode(reflect.ValueOf(input.Text))
+		}
+	case durationType:
+		return scalarNode(v.Interface().(time.Duration).String())
+	case byteSizeType:
+		return scalarNode(v.Interface().(framework.ByteSize).String())
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		return encodeNode(v.Elem())
+
+	case reflect.Struct:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		encodeFields(node, v)
+		return node
+
+	case reflect.Map:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int { return strings.Compare(a.String(), b.String()) })
+		for _, k := range keys {
+			node.Content = append(node.Content, scalarNode(k.String()), encodeNode(v.MapIndex(k)))
+		}
+		return node
+
+	case reflect.Slice:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for i := range v.Len() {
+			item := encodeNode(v.Index(i))
+			if item.Kind != yaml.ScalarNode || item.Style == yaml.LiteralStyle {
+				node.Style = 0
+			}
+			node.Content = append(node.Content, item)
+		}
+		return node
+
+	case reflect.String:
+		node := scalarNode(v.String())
+		if strings.Contains(v.String(), "\n") {
+			node.Style = yaml.LiteralStyle
+		}
+		return node
+
+	default:
+		node := &yaml.Node{}
+		_ = node.Encode(v.Interface())
+		return node
+	}
+}
+
+// encodeFields appends non-empty fields of the struct to the mapping node, inline fields are flattened.
+func encodeFields(node *yaml.Node, v reflect.Value) {
+	for i := range v.NumField() {
+		f, value := v.Type().Field(i), v.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		switch {
+		case name == "-":
+			continue
+		case opts == "inline":
+			encodeFields(node, value)
+			continue
+		case name == "":
+			name = strings.ToLower(f.Name)
+		}
+
+		if value.IsZero() || (value.Kind() == reflect.Slice || value.Kind() == reflect.Map) && value.Len() == 0 {
+			continue
+		}
+		node.Content = append(node.Content, scalarNode(name), encodeNode(value))
+	}
+}
+
+func scalarNode(value string) *yaml.Node {
+	// explicit tag makes encoder quote strings, that would be read as other types, e.g. `true`
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
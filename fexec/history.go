@@ -0,0 +1,177 @@
+package fexec
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/roboslone/go-framework/v2"
+	"github.com/roboslone/go-framework/v2/internal/git"
+)
+
+const (
+	// HistoryDir is a directory next to the config, that holds local state of fexec.
+	HistoryDir = ".fexec"
+
+	// HistoryFileName is a name of file within HistoryDir, that holds a JSON line per run.
+	HistoryFileName = "history.jsonl"
+)
+
+// HistoryRun is a record of a single fexec run.
+type HistoryRun struct {
+	Started   time.Time        `json:"started"`
+	Duration  time.Duration    `json:"duration"`
+	Requested []string         `json:"requested"`
+	Status    framework.Status `json:"status"`
+	Host      string           `json:"host,omitempty"`
+
+	// GitSHA is a commit checked out during the run, Dirty is set if there were uncommitted changes.
+	GitSHA string `json:"git_sha,omitempty"`
+	Dirty  bool   `json:"dirty,omitempty"`
+
+	Modules []HistoryModule `json:"modules"`
+
+	// Args are command line arguments, that reproduce the run.
+	Args []string `json:"args,omitempty"`
+}
+
+// HistoryModule is a record of a single module run.
+type HistoryModule struct {
+	Module   string           `json:"module"`
+	Status   framework.Status `json:"status"`
+	Started  time.Time        `json:"started,omitzero"`
+	Duration time.Duration    `json:"duration"`
+	Usage    *framework.Usage `json:"usage,omitempty"`
+}
+
+// HistoryRecorder is a `framework.Reporter`, that records finished modules into a `HistoryRun`.
+type HistoryRecorder struct {
+	lock sync.Mutex
+	run  HistoryRun
+}
+
+// NewHistoryRecorder starts recording a run of requested modules. Host and git commit are recorded,
+// if available. Git repository is looked up in `dir`.
+func NewHistoryRecorder(ctx context.Context, dir string, requested []string) *HistoryRecorder {
+	r := &HistoryRecorder{run: HistoryRun{
+		Started:   time.Now(),
+		Requested: requested,
+	}}
+
+	r.run.Host, _ = os.Hostname()
+	if sha, err := git.Head(ctx, dir); err == nil {
+		r.run.GitSHA = sha
+		r.run.Dirty, _ = git.Dirty(ctx, dir)
+	}
+	return r
+}
+
+// SetArgs records command line arguments, that reproduce the run.
+func (r *HistoryRecorder) SetArgs(args []string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.run.Args = args
+}
+
+func (r *HistoryRecorder) Report(module string, event framework.Event) {
+	finish, ok := event.(*framework.FinishEvent)
+	if !ok {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	m := HistoryModule{
+		Module:   module,
+		Status:   finish.Status,
+		Started:  finish.Time.Add(-finish.Duration),
+		Duration: finish.Duration,
+	}
+	if finish.Status != framework.StatusSkipped {
+		m.Usage = &finish.Usage
+	}
+	r.run.Modules = append(r.run.Modules, m)
+}
+
+// Save appends the run to history file at given path, creating its directory if needed.
+// Run status is derived from `err` returned by the application.
+func (r *HistoryRecorder) Save(path string, err error) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.run.Duration = time.Since(r.run.Started)
+	r.run.Status = framework.StatusSucceeded
+	if err != nil {
+		r.run.Status = framework.StatusFailed
+	}
+	slices.SortFunc(r.run.Modules, func(a, b HistoryModule) int {
+		return a.Started.Compare(b.Started)
+	})
+
+	line, err := json.Marshal(r.run)
+	if err != nil {
+		return fmt.Errorf("encoding run: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating history dir: %w", err)
+	}
+	// history is local, keep it out of version control
+	if err = os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("*\n"), 0o644); err != nil {
+		return fmt.Errorf("writing .gitignore: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening history: %w", err)
+	}
+	defer f.Close()
+
+	if _, err = f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing history: %w", err)
+	}
+	return f.Close()
+}
+
+// ReadHistory reads runs from history file at given path, oldest first.
+// Missing file is treated as empty history, malformed lines are skipped.
+func ReadHistory(path string) ([]HistoryRun, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening history: %w", err)
+	}
+	defer f.Close()
+
+	var runs []HistoryRun
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		run := HistoryRun{}
+		if json.Unmarshal([]byte(line), &run) == nil {
+			runs = append(runs, run)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+	return runs, nil
+}
@@ -0,0 +1,57 @@
+package fexec_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	framework "github.com/roboslone/go-framework/v2"
+	"github.com/roboslone/go-framework/v2/fexec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), fexec.HistoryDir, fexec.HistoryFileName)
+
+	record := func(status framework.Status, d time.Duration) {
+		r := fexec.NewHistoryRecorder(t.Context(), t.TempDir(), []string{"test"})
+		r.Report("test", &framework.FinishEvent{Time: time.Now(), Status: status, Duration: d})
+		r.SetArgs([]string{"-v", "test"})
+
+		var err error
+		if status == framework.StatusFailed {
+			err = errors.New("failed")
+		}
+		require.NoError(t, r.Save(path, err))
+	}
+	for i := range 10 {
+		record(framework.StatusSucceeded, time.Duration(i+1)*time.Second)
+	}
+	record(framework.StatusSkipped, 0)
+
+	runs, err := fexec.ReadHistory(path)
+	require.NoError(t, err)
+	require.Len(t, runs, 11)
+	require.Equal(t, []string{"-v", "test"}, runs[10].Args)
+
+	// failures are only flaky if the module succeeded at the same commit
+	runs[0].GitSHA, runs[1].GitSHA = "a", "a"
+	runs[1].Modules[0].Status = framework.StatusFailed
+	runs[2].GitSHA = "b"
+	runs[2].Modules[0].Status = framework.StatusFailed
+	runs[2].Modules[0].Usage.MaxRSS = 512 << 20
+
+	stats := fexec.ComputeStats(runs)
+	require.Len(t, stats, 1)
+	require.Equal(t, fexec.ModuleStats{
+		Module:        "test",
+		Runs:          10,
+		Failures:      2,
+		FlakyFailures: 1,
+		P50:           6 * time.Second,
+		P95:           10 * time.Second,
+		MaxRSS:        512 << 20,
+	}, stats[0])
+	require.InDelta(t, 0.1, stats[0].FlakyRate(), 0.001)
+}
@@ -0,0 +1,431 @@
+package fexec
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/roboslone/go-framework/v2"
+)
+
+var (
+	// makefileNames are looked up in the same order as GNU make does.
+	makefileNames = []string{"GNUmakefile", "makefile", "Makefile"}
+
+	makeAssignPattern = regexp.MustCompile(`^([^\s:#=!?+]+)\s*(:::=|::=|:=|\?=|\+=|!=|=)\s*(.*)$`)
+	makeNamePattern   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// FindMakefile returns path to the makefile located directly in `dir`.
+func FindMakefile(dir string) (string, error) {
+	for _, name := range makefileNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("stat %q: %w", path, err)
+		}
+	}
+	return "", fmt.Errorf("%w: no makefile in %q (searched for %s)", os.ErrNotExist, dir, strings.Join(makefileNames, ", "))
+}
+
+// ImportMakefile converts simple targets of the makefile into commands.
+//
+// Every recipe line becomes a step, as make runs each line in its own shell, using `sh -c` just like make does.
+// Prerequisites, that are targets themselves, become dependencies, other prerequisites are dropped.
+// Variables become vars, `$(shell ...)` ones are run by shell, exported ones are also set in env. Automatic variables `$@`, `$<` and `$^` are substituted.
+//
+// Targets and variables, that can't be converted (e.g. pattern rules, make functions or conditionals),
+// are left out and described in returned messages.
+func ImportMakefile(path string) (*CommandConfig, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading makefile: %w", err)
+	}
+
+	p := &makefileParser{vars: make(map[string]Var), rules: make(map[string]*makeRule)}
+	p.parse(strings.Split(string(data), "\n"))
+
+	cfg := &CommandConfig{
+		Vars:     p.vars,
+		Shell:    []string{"sh", "-c"},
+		Commands: make(map[string]Command, len(p.rules)),
+	}
+	for _, name := range slices.Compact(slices.Sorted(slices.Values(p.exported))) {
+		if _, ok := p.vars[name]; ok {
+			cfg.Env = append(cfg.Env, name+"=${"+name+"}")
+		}
+	}
+
+	for _, target := range p.order {
+		rule := p.rules[target]
+
+		command, err := rule.command(p.rules)
+		if err != nil {
+			p.skip(rule.line, "target %q: %s", target, err)
+			continue
+		}
+		cfg.Commands[target] = command
+	}
+
+	// dependencies on targets left out are dropped
+	for name, command := range cfg.Commands {
+		command.DependsOn = slices.DeleteFunc(command.DependsOn, func(d string) bool {
+			if _, ok := cfg.Commands[d]; ok {
+				return false
+			}
+			p.skip(p.rules[name].line, "target %q: dependency on target %q dropped", name, d)
+			return true
+		})
+		if command.IsNoop() && len(command.DependsOn) == 0 {
+			p.skip(p.rules[name].line, "target %q: nothing to run", name)
+			delete(cfg.Commands, name)
+			continue
+		}
+		cfg.Commands[name] = command
+	}
+	slices.SortStableFunc(p.skipped, func(a, b makeSkip) int { return a.line - b.line })
+
+	messages := make([]string, 0, len(p.skipped))
+	for _, s := range p.skipped {
+		messages = append(messages, fmt.Sprintf("line %d: %s", s.line, s.message))
+	}
+	return cfg, messages, nil
+}
+
+type makefileParser struct {
+	vars    map[string]Var
+	rules   map[string]*makeRule
+	order   []string
+	skipped []makeSkip
+
+	// exported are names of variables passed to recipes as environment.
+	exported []string
+
+	// conditional is a nesting level of `ifeq` and alike, anything defined within conditionals is skipped.
+	conditional int
+}
+
+type makeRule struct {
+	target  string
+	prereqs []string
+	recipe  []string
+	line    int
+}
+
+type makeSkip struct {
+	line    int
+	message string
+}
+
+func (p *makefileParser) skip(line int, format string, args ...any) {
+	p.skipped = append(p.skipped, makeSkip{line, fmt.Sprintf(format, args...)})
+}
+
+func (p *makefileParser) parse(lines []string) {
+	// current receives recipe lines, it's not registered for skipped rules
+	var current *makeRule
+
+	for i := 0; i < len(lines); i++ {
+		number, line := i+1, strings.TrimSuffix(lines[i], "\r")
+
+		if strings.HasPrefix(line, "\t") {
+			if current != nil {
+				recipe := line[1:]
+				for strings.HasSuffix(recipe, `\`) && i+1 < len(lines) {
+					i++
+					recipe += "\n" + strings.TrimPrefix(strings.TrimSuffix(lines[i], "\r"), "\t")
+				}
+				current.recipe = append(current.recipe, recipe)
+			}
+			continue
+		}
+
+		for strings.HasSuffix(line, `\`) && i+1 < len(lines) {
+			i++
+			line = strings.TrimSuffix(line, `\`) + " " + strings.TrimSpace(lines[i])
+		}
+		if j := strings.Index(line, "#"); j >= 0 {
+			line = line[:j]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		word, rest, _ := strings.Cut(line, " ")
+		switch word {
+		case "ifeq", "ifneq", "ifdef", "ifndef":
+			p.conditional++
+			current = nil
+			continue
+		case "else":
+			current = nil
+			continue
+		case "endif":
+			p.conditional = max(p.conditional-1, 0)
+			current = nil
+			continue
+		case "define":
+			p.skip(number, "variable %q: multi-line variables are not supported", strings.TrimSpace(rest))
+			for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "endef" {
+				i++
+			}
+			i++
+			current = nil
+			continue
+		case "include", "-include", "sinclude":
+			p.skip(number, "include is not supported: %s", rest)
+			current = nil
+			continue
+		case "export", "override":
+			m := makeAssignPattern.FindStringSubmatch(strings.TrimSpace(rest))
+			if word == "export" {
+				if m != nil {
+					p.exported = append(p.exported, m[1])
+				} else {
+					p.exported = append(p.exported, strings.Fields(rest)...)
+				}
+			}
+			if m == nil {
+				current = nil
+				continue
+			}
+			line = strings.TrimSpace(rest)
+		case "unexport", "vpath":
+			current = nil
+			continue
+		}
+
+		current = nil
+		if m := makeAssignPattern.FindStringSubmatch(line); m != nil {
+			p.assign(number, m[1], m[2], m[3])
+			continue
+		}
+
+		targets, prereqs, ok := strings.Cut(line, ":")
+		if !ok {
+			p.skip(number, "unsupported line: %s", line)
+			continue
+		}
+		current = p.rule(number, strings.Fields(targets), strings.TrimPrefix(prereqs, ":"))
+	}
+}
+
+// rule registers the rule, unless it can't be converted. Returned rule receives recipe lines.
+func (p *makefileParser) rule(number int, targets []string, prereqs string) *makeRule {
+	rule := &makeRule{line: number}
+	prereqs, inline, hasInline := strings.Cut(prereqs, ";")
+	if hasInline {
+		rule.recipe = append(rule.recipe, strings.TrimSpace(inline))
+	}
+
+	var reason string
+	switch {
+	case len(targets) == 0:
+		reason = "rule without targets"
+	case slices.ContainsFunc(targets, func(t string) bool { return strings.HasPrefix(t, ".") }):
+		// special targets, e.g. `.PHONY`
+		return rule
+	case len(targets) > 1:
+		reason = "multiple targets in a rule are not supported"
+	case strings.Contains(targets[0], "%"):
+		reason = "pattern rules are not supported"
+	case strings.Contains(targets[0], "$"):
+		reason = "targets with variables are not supported"
+	case strings.Contains(prereqs, "$"):
+		reason = "prerequisites with variables are not supported"
+	case makeAssignPattern.MatchString(strings.TrimSpace(prereqs)):
+		reason = "target-specific variables are not supported"
+	case p.conditional > 0:
+		reason = "defined within a conditional"
+	}
+	if reason != "" {
+		for _, t := range targets {
+			p.skip(number, "target %q: %s", t, reason)
+		}
+		return rule
+	}
+
+	rule.target = targets[0]
+	for _, d := range strings.Fields(prereqs) {
+		if d != "|" {
+			rule.prereqs = append(rule.prereqs, d)
+		}
+	}
+
+	if existing, ok := p.rules[rule.target]; ok {
+		existing.prereqs = append(existing.prereqs, rule.prereqs...)
+		existing.recipe = append(existing.recipe, rule.recipe...)
+		return existing
+	}
+	p.rules[rule.target] = rule
+	p.order = append(p.order, rule.target)
+	return rule
+}
+
+func (p *makefileParser) assign(number int, name, op, value string) {
+	if !makeNamePattern.MatchString(name) {
+		p.skip(number, "variable %q: name can't be used as a var", name)
+		return
+	}
+	if p.conditional > 0 {
+		p.skip(number, "variable %q: defined within a conditional", name)
+		return
+	}
+
+	v := Var{}
+	if sh, ok := strings.CutPrefix(value, "$(shell "); ok && strings.HasSuffix(sh, ")") && matchingParen(value, 1) == len(value)-1 {
+		op, value = "!=", strings.TrimSuffix(sh, ")")
+	}
+
+	converted, err := convertMakeRefs(value, nil)
+	if err != nil {
+		p.skip(number, "variable %q: %s", name, err)
+		return
+	}
+	if op == "!=" {
+		v.Sh = converted
+	} else {
+		v.Value = converted
+	}
+
+	existing, defined := p.vars[name]
+	switch {
+	case op == "?=" && defined:
+		return
+	case op == "+=" && defined:
+		if existing.Sh != "" || v.Sh != "" {
+			p.skip(number, "variable %q: appending to shell variables is not supported", name)
+			return
+		}
+		v.Value = strings.TrimSpace(existing.Value + " " + v.Value)
+	}
+	p.vars[name] = v
+}
+
+// command converts the rule into a command, every recipe line becomes a step.
+func (r *makeRule) command(rules map[string]*makeRule) (Command, error) {
+	c := Command{}
+	for _, d := range r.prereqs {
+		if _, ok := rules[d]; ok && !slices.Contains(c.DependsOn, d) {
+			c.DependsOn = append(c.DependsOn, d)
+		}
+	}
+
+	var steps []framework.CommandStep
+	for _, line := range r.recipe {
+		script, ignoreErrors := strings.TrimSpace(line), false
+		for len(script) > 0 && strings.ContainsRune("@-+", rune(script[0])) {
+			ignoreErrors = ignoreErrors || script[0] == '-'
+			script = strings.TrimSpace(script[1:])
+		}
+		if script == "" {
+			continue
+		}
+
+		script, err := convertMakeRefs(script, r)
+		if err != nil {
+			return Command{}, err
+		}
+		if ignoreErrors {
+			script = "(" + script + "\n) || true"
+		}
+		steps = append(steps, framework.CommandStep{Script: script})
+	}
+
+	if len(steps) == 1 {
+		c.Script = steps[0].Script
+	} else {
+		c.Steps = steps
+	}
+	return c, nil
+}
+
+// convertMakeRefs converts make references into ones understood by fexec and shell:
+// `$(NAME)` and `${NAME}` become `${NAME}`, `$(shell cmd)` becomes `$(cmd)` and `$$` becomes `$`.
+// Automatic variables are substituted, if the rule is given.
+func convertMakeRefs(s string, rule *makeRule) (string, error) {
+	b := strings.Builder{}
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		switch c := s[i]; {
+		case c == '$':
+			b.WriteByte('$')
+
+		case c == '(' || c == '{':
+			end := matchingParen(s, i)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated reference: %s", s[i-1:])
+			}
+			inner := s[i+1 : end]
+			i = end
+
+			switch {
+			case inner == "MAKE":
+				b.WriteString("make")
+			case inner == "CURDIR":
+				b.WriteString("$(pwd)")
+			case strings.HasPrefix(inner, "shell "):
+				converted, err := convertMakeRefs(strings.TrimPrefix(inner, "shell "), rule)
+				if err != nil {
+					return "", err
+				}
+				b.WriteString("$(" + converted + ")")
+			case makeNamePattern.MatchString(inner):
+				b.WriteString("${" + inner + "}")
+			default:
+				return "", fmt.Errorf("make functions are not supported: $(%s)", inner)
+			}
+
+		case c == '@' || c == '<' || c == '^':
+			if rule == nil {
+				return "", fmt.Errorf("automatic variable $%c outside of a recipe", c)
+			}
+			switch c {
+			case '@':
+				b.WriteString(rule.target)
+			case '<':
+				if len(rule.prereqs) > 0 {
+					b.WriteString(rule.prereqs[0])
+				}
+			case '^':
+				b.WriteString(strings.Join(slices.Compact(slices.Clone(rule.prereqs)), " "))
+			}
+
+		case makeNamePattern.MatchString(string(c)):
+			b.WriteString("${" + string(c) + "}")
+
+		default:
+			return "", fmt.Errorf("unsupported reference: $%c", c)
+		}
+	}
+	return b.String(), nil
+}
+
+// matchingParen returns index of the bracket closing the one at `open`, or -1.
+func matchingParen(s string, open int) int {
+	closing := map[byte]byte{'(': ')', '{': '}'}[s[open]]
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case s[open]:
+			depth++
+		case closing:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
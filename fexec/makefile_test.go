@@ -0,0 +1,98 @@
+package fexec_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	framework "github.com/roboslone/go-framework/v2"
+	"github.com/roboslone/go-framework/v2/fexec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportMakefile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Makefile"), []byte(`# comment
+BIN := bin/app
+VERSION ?= $(shell git describe --tags)
+GOFLAGS = -trimpath
+GOFLAGS += -v
+export GOFLAGS
+export CGO_ENABLED = 0
+SRCS = $(wildcard *.go)
+
+.PHONY: all build test lint
+
+all: build test
+
+build: main.go | tools
+	@echo building $@ from $<
+	go build $(GOFLAGS) -o $(BIN) .
+
+tools:
+	go install golang.org/x/tools/cmd/stringer@latest
+
+test: build
+	-go test ./... \
+		-race
+	cd sub && $(MAKE) test
+
+lint: ; golangci-lint run $$PWD
+
+%.o: %.c
+	cc -c $<
+
+ifeq ($(CI),true)
+ci: lint
+endif
+
+docs: missing
+`), 0o644))
+
+	path, err := fexec.FindMakefile(dir)
+	require.NoError(t, err)
+
+	cfg, skipped, err := fexec.ImportMakefile(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`line 8: variable "SRCS": make functions are not supported: $(wildcard *.go)`,
+		`line 28: target "%.o": pattern rules are not supported`,
+		`line 32: target "ci": defined within a conditional`,
+		`line 35: target "docs": nothing to run`,
+	}, skipped)
+
+	require.Equal(t, map[string]fexec.Var{
+		"BIN":         {Value: "bin/app"},
+		"VERSION":     {Sh: "git describe --tags"},
+		"GOFLAGS":     {Value: "-trimpath -v"},
+		"CGO_ENABLED": {Value: "0"},
+	}, cfg.Vars)
+	require.Equal(t, []string{"CGO_ENABLED=${CGO_ENABLED}", "GOFLAGS=${GOFLAGS}"}, cfg.Env)
+	require.Equal(t, []string{"sh", "-c"}, cfg.Shell)
+
+	require.Equal(t, []string{"build", "test"}, cfg.Commands["all"].DependsOn)
+	require.True(t, cfg.Commands["all"].IsNoop())
+
+	require.Equal(t, []string{"tools"}, cfg.Commands["build"].DependsOn)
+	require.Equal(t, []framework.CommandStep{
+		{Script: "echo building build from main.go"},
+		{Script: "go build ${GOFLAGS} -o ${BIN} ."},
+	}, cfg.Commands["build"].Steps)
+
+	require.Equal(t, []framework.CommandStep{
+		{Script: "(go test ./... \\\n\t-race\n) || true"},
+		{Script: "cd sub && make test"},
+	}, cfg.Commands["test"].Steps)
+
+	require.Equal(t, "golangci-lint run $PWD", cfg.Commands["lint"].Script)
+
+	// imported config is valid
+	data, err := fexec.EncodeConfig(cfg)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".fexec.yaml"), data, 0o644))
+
+	parsed, err := fexec.ParseConfig(filepath.Join(dir, ".fexec.yaml"))
+	require.NoError(t, err)
+	require.Equal(t, cfg.Vars["VERSION"].Sh, parsed.Vars["VERSION"].Sh)
+	require.Equal(t, cfg.Commands["test"].Steps, parsed.Commands["test"].Steps)
+}
@@ -0,0 +1,223 @@
+package fexec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/roboslone/go-framework/v2"
+)
+
+const (
+	// DefaultNotifyAfter is a threshold of notifications, unless config or command line sets one.
+	DefaultNotifyAfter = 10 * time.Second
+
+	notifyTimeout = time.Minute
+)
+
+// NotifyConfig describes notifications sent once a run finishes.
+type NotifyConfig struct {
+	// After is a minimum duration of the run, shorter runs don't notify. Defaults to `DefaultNotifyAfter`.
+	After time.Duration `yaml:"after"`
+
+	// Terminal rings the bell and sends a desktop notification (OSC 9), if output is a terminal.
+	Terminal bool `yaml:"terminal"`
+
+	// OnSuccess and OnFailure are scripts run by the config shell in its directory,
+	// with summary of the run in `FEXEC_*` env vars, see `Notification.Environ`.
+	OnSuccess string `yaml:"on_success"`
+	OnFailure string `yaml:"on_failure"`
+
+	// Webhook is an URL, that receives `Notification` as JSON via POST.
+	Webhook string `yaml:"webhook"`
+}
+
+// Enabled reports whether any notification is configured.
+func (c NotifyConfig) Enabled() bool {
+	return c.Terminal || c.OnSuccess != "" || c.OnFailure != "" || c.Webhook != ""
+}
+
+// Validate reports negative threshold and invalid webhook URL.
+func (c NotifyConfig) Validate() error {
+	var errs []error
+	if c.After < 0 {
+		errs = append(errs, fmt.Errorf("notify: after must not be negative, got %s", c.After))
+	}
+	if c.Webhook != "" {
+		if u, err := url.Parse(c.Webhook); err != nil {
+			errs = append(errs, fmt.Errorf("notify: webhook: %w", err))
+		} else if u.Scheme != "http" && u.Scheme != "https" {
+			errs = append(errs, fmt.Errorf("notify: webhook: expected http or https URL, got %q", c.Webhook))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Notification is a summary of a finished run.
+type Notification struct {
+	Status    framework.Status `json:"status"`
+	Duration  time.Duration    `json:"duration"`
+	Requested []string         `json:"requested"`
+	Failed    []string         `json:"failed,omitempty"`
+	Error     string           `json:"error,omitempty"`
+
+	// Text is a human-readable summary, the field name is understood by Slack and Mattermost webhooks.
+	Text string `json:"text"`
+}
+
+// Environ returns the notification as env vars for hooks.
+func (n Notification) Environ() []string {
+	return []string{
+		"FEXEC_STATUS=" + string(n.Status),
+		"FEXEC_DURATION=" + n.Duration.Round(time.Millisecond).String(),
+		"FEXEC_DURATION_SECONDS=" + fmt.Sprintf("%.3f", n.Duration.Seconds()),
+		"FEXEC_REQUESTED=" + strings.Join(n.Requested, " "),
+		"FEXEC_FAILED=" + strings.Join(n.Failed, " "),
+		"FEXEC_ERROR=" + n.Error,
+		"FEXEC_SUMMARY=" + n.Text,
+	}
+}
+
+// Notifier is a `framework.Reporter`, that collects failed modules and notifies about the finished run,
+// see `NotifyConfig`.
+type Notifier struct {
+	config    NotifyConfig
+	dir       string
+	shell     []string
+	requested []string
+	started   time.Time
+
+	// Out receives terminal notifications, os.Stdout by default.
+	Out io.Writer
+
+	lock   sync.Mutex
+	failed []string
+}
+
+// NewNotifier starts timing a run of requested modules. Hooks are run by `shell` within `dir`,
+// `framework.DefaultShell` is used if shell is empty.
+func NewNotifier(config NotifyConfig, dir string, shell []string, requested []string) *Notifier {
+	if len(shell) == 0 {
+		shell = framework.DefaultShell
+	}
+	if config.After == 0 {
+		config.After = DefaultNotifyAfter
+	}
+	return &Notifier{
+		config:    config,
+		dir:       dir,
+		shell:     shell,
+		requested: requested,
+		started:   time.Now(),
+		Out:       os.Stdout,
+	}
+}
+
+func (n *Notifier) Report(module string, event framework.Event) {
+	if finish, ok := event.(*framework.FinishEvent); ok && finish.Status == framework.StatusFailed {
+		n.lock.Lock()
+		defer n.lock.Unlock()
+
+		n.failed = append(n.failed, module)
+	}
+}
+
+// Notify sends configured notifications, unless the run took less than `After`.
+// Run status is derived from `err` returned by the application. Hooks and webhook run even if `ctx` is cancelled,
+// so that they can clean up after an interrupted run. Errors of all notifications are joined.
+func (n *Notifier) Notify(ctx context.Context, err error) error {
+	notification := n.notification(err)
+	if notification.Duration < n.config.After {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notifyTimeout)
+	defer cancel()
+
+	var errs []error
+	if n.config.Terminal {
+		if f, ok := n.Out.(*os.File); !ok || isatty.IsTerminal(f.Fd()) {
+			// OSC 9 is shown as a desktop notification by iTerm2, Windows Terminal, kitty and others
+			fmt.Fprintf(n.Out, "\a\x1b]9;%s\x1b\\", strings.NewReplacer("\x1b", "", "\a", "").Replace(notification.Text))
+		}
+	}
+
+	script := n.config.OnSuccess
+	if notification.Status == framework.StatusFailed {
+		script = n.config.OnFailure
+	}
+	if script != "" {
+		cmd := exec.CommandContext(ctx, n.shell[0], append(slices.Clone(n.shell[1:]), script)...)
+		cmd.Dir = n.dir
+		cmd.Env = append(os.Environ(), notification.Environ()...)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if hookErr := cmd.Run(); hookErr != nil {
+			errs = append(errs, fmt.Errorf("%s hook: %w", notification.Status, hookErr))
+		}
+	}
+
+	if n.config.Webhook != "" {
+		if webhookErr := postWebhook(ctx, n.config.Webhook, notification); webhookErr != nil {
+			errs = append(errs, fmt.Errorf("webhook: %w", webhookErr))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (n *Notifier) notification(err error) Notification {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	result := Notification{
+		Status:    framework.StatusSucceeded,
+		Duration:  time.Since(n.started),
+		Requested: n.requested,
+		Failed:    slices.Sorted(slices.Values(n.failed)),
+	}
+	result.Text = fmt.Sprintf("fexec %s succeeded in %s", strings.Join(n.requested, " "), result.Duration.Round(time.Second))
+	if err != nil {
+		result.Status, result.Error = framework.StatusFailed, err.Error()
+		result.Text = fmt.Sprintf("fexec %s failed in %s", strings.Join(n.requested, " "), result.Duration.Round(time.Second))
+		if len(result.Failed) > 0 {
+			result.Text += ": " + strings.Join(result.Failed, ", ")
+		}
+	}
+	return result
+}
+
+func postWebhook(ctx context.Context, webhook string, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("marshaling notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
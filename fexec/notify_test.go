@@ -0,0 +1,77 @@
+package fexec_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	framework "github.com/roboslone/go-framework/v2"
+	"github.com/roboslone/go-framework/v2/fexec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifier(t *testing.T) {
+	received := make(chan fexec.Notification, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n fexec.Notification
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&n))
+		received <- n
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	config := fexec.NotifyConfig{
+		After:     time.Nanosecond,
+		Terminal:  true,
+		OnSuccess: `echo "$FEXEC_STATUS" > success`,
+		OnFailure: `echo "$FEXEC_STATUS $FEXEC_FAILED" > failure`,
+		Webhook:   server.URL,
+	}
+	require.NoError(t, config.Validate())
+
+	n := fexec.NewNotifier(config, dir, nil, []string{"build", "test"})
+	out := &bytes.Buffer{}
+	n.Out = out
+	n.Report("lint", &framework.FinishEvent{Status: framework.StatusSucceeded})
+	n.Report("test", &framework.FinishEvent{Status: framework.StatusFailed})
+	require.NoError(t, n.Notify(t.Context(), errors.New("test failed")))
+
+	require.Equal(t, "\a\x1b]9;fexec build test failed in 0s: test\x1b\\", out.String())
+
+	data, err := os.ReadFile(filepath.Join(dir, "failure"))
+	require.NoError(t, err)
+	require.Equal(t, "failed test", strings.TrimSpace(string(data)))
+	require.NoFileExists(t, filepath.Join(dir, "success"))
+
+	notification := <-received
+	require.Equal(t, framework.StatusFailed, notification.Status)
+	require.Equal(t, []string{"build", "test"}, notification.Requested)
+	require.Equal(t, []string{"test"}, notification.Failed)
+	require.Equal(t, "test failed", notification.Error)
+
+	// runs shorter than threshold don't notify
+	config.After = time.Hour
+	n = fexec.NewNotifier(config, dir, nil, []string{"build"})
+	n.Out = out
+	out.Reset()
+	require.NoError(t, n.Notify(t.Context(), nil))
+	require.Empty(t, out.String())
+	require.NoFileExists(t, filepath.Join(dir, "success"))
+
+	// threshold defaults to 10s, even if notifications are only configured
+	config.After = 0
+	n = fexec.NewNotifier(config, dir, nil, []string{"build"})
+	n.Out = out
+	require.NoError(t, n.Notify(t.Context(), nil))
+	require.Empty(t, out.String())
+	require.NoFileExists(t, filepath.Join(dir, "success"))
+
+	require.Error(t, fexec.NotifyConfig{After: -time.Second, Webhook: "ftp://example.com"}.Validate())
+}
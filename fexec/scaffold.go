@@ -0,0 +1,256 @@
+package fexec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// scaffoldStages are modules generated for each detected toolchain, in order of execution.
+var scaffoldStages = []string{"install", "lint", "test", "build"}
+
+// Scaffold returns a starter config for the project in `dir`, detected from its files:
+// go.mod, package.json, Cargo.toml, Makefile, Dockerfile and golangci-lint configs.
+//
+// Each toolchain contributes `install`, `lint`, `test` and `build` commands, where applicable. Makefile targets
+// of the same names take precedence. If several toolchains provide the same command, their commands are
+// namespaced (e.g. `go:test`) and aggregated by the command of plain name. `ci` depends on everything but install.
+func Scaffold(dir string) (*CommandConfig, error) {
+	var toolchains []*toolchain
+	for _, detect := range []func(string) (*toolchain, error){detectGo, detectNode, detectCargo} {
+		t, err := detect(dir)
+		if err != nil {
+			return nil, err
+		}
+		if t != nil {
+			toolchains = append(toolchains, t)
+		}
+	}
+
+	// make targets replace stages of other toolchains
+	if t, err := detectMake(dir); err != nil {
+		return nil, err
+	} else if t != nil {
+		for _, other := range toolchains {
+			for stage := range t.stages {
+				delete(other.stages, stage)
+			}
+		}
+		toolchains = append(toolchains, t)
+	}
+	toolchains = slices.DeleteFunc(toolchains, func(t *toolchain) bool { return len(t.stages) == 0 })
+
+	providers := make(map[string][]string)
+	for _, t := range toolchains {
+		for stage := range t.stages {
+			providers[stage] = append(providers[stage], t.name)
+		}
+	}
+	// name returns name of the toolchain stage, namespaced if several toolchains provide the stage
+	name := func(t *toolchain, stage string) string {
+		if len(providers[stage]) > 1 {
+			return t.name + namespaceSeparator + stage
+		}
+		return stage
+	}
+
+	cfg := &CommandConfig{Commands: make(map[string]Command)}
+	for _, t := range toolchains {
+		for stage, c := range t.stages {
+			// stages depend on install of their own toolchain, or on install of the project
+			if _, ok := t.stages["install"]; ok && stage != "install" {
+				c = withDependencies(c, name(t, "install"))
+			} else if len(providers["install"]) > 0 && stage != "install" {
+				c = withDependencies(c, "install")
+			}
+			cfg.Commands[name(t, stage)] = c
+		}
+	}
+	for stage, names := range providers {
+		if len(names) > 1 {
+			var aggregate Command
+			for _, n := range names {
+				aggregate = withDependencies(aggregate, n+namespaceSeparator+stage)
+			}
+			cfg.Commands[stage] = aggregate
+		}
+	}
+
+	docker, err := detectDocker(dir)
+	if err != nil {
+		return nil, err
+	}
+	if docker != nil {
+		cfg.Commands["docker"] = *docker
+	}
+
+	if len(cfg.Commands) == 0 {
+		return nil, fmt.Errorf("no known project files in %q (go.mod, package.json, Cargo.toml, Makefile or Dockerfile)", dir)
+	}
+
+	var ci Command
+	for _, name := range []string{"lint", "test", "build", "docker"} {
+		if _, ok := cfg.Commands[name]; ok {
+			ci = withDependencies(ci, name)
+		}
+	}
+	if len(ci.DependsOn) > 0 {
+		cfg.Commands["ci"] = ci
+	}
+
+	return cfg, nil
+}
+
+// toolchain maps scaffold stages to commands.
+type toolchain struct {
+	name   string
+	stages map[string]Command
+}
+
+func withDependencies(c Command, deps ...string) Command {
+	c.DependsOn = append(c.DependsOn, deps...)
+	return c
+}
+
+func argv(args ...string) Command {
+	c := Command{}
+	c.Command = args
+	return c
+}
+
+func exists(dir string, names ...string) (string, error) {
+	for _, name := range names {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return name, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("stat %q: %w", name, err)
+		}
+	}
+	return "", nil
+}
+
+func detectGo(dir string) (*toolchain, error) {
+	if found, err := exists(dir, "go.mod"); found == "" || err != nil {
+		return nil, err
+	}
+
+	lint := argv("go", "vet", "./...")
+	golangci, err := exists(dir, ".golangci.yml", ".golangci.yaml", ".golangci.toml", ".golangci.json")
+	if err != nil {
+		return nil, err
+	}
+	if golangci != "" {
+		lint = argv("golangci-lint", "run")
+	}
+
+	return &toolchain{name: "go", stages: map[string]Command{
+		"install": argv("go", "mod", "download"),
+		"lint":    lint,
+		"test":    argv("go", "test", "./..."),
+		"build":   argv("go", "build", "./..."),
+	}}, nil
+}
+
+func detectNode(dir string) (*toolchain, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading package.json: %w", err)
+	}
+
+	pkg := struct {
+		Scripts map[string]string `json:"scripts"`
+	}{}
+	if err = json.Unmarshal(data, &pkg); err != nil {
+		return nil, fmt.Errorf("parsing package.json: %w", err)
+	}
+
+	lockfile, err := exists(dir, "pnpm-lock.yaml", "yarn.lock", "bun.lock", "bun.lockb", "package-lock.json")
+	if err != nil {
+		return nil, err
+	}
+
+	manager, install := "npm", argv("npm", "install")
+	switch lockfile {
+	case "pnpm-lock.yaml":
+		manager, install = "pnpm", argv("pnpm", "install", "--frozen-lockfile")
+	case "yarn.lock":
+		manager, install = "yarn", argv("yarn", "install", "--frozen-lockfile")
+	case "bun.lock", "bun.lockb":
+		manager, install = "bun", argv("bun", "install", "--frozen-lockfile")
+	case "package-lock.json":
+		install = argv("npm", "ci")
+	}
+
+	t := &toolchain{name: "node", stages: map[string]Command{"install": install}}
+	for _, stage := range scaffoldStages[1:] {
+		if _, ok := pkg.Scripts[stage]; ok {
+			t.stages[stage] = argv(manager, "run", stage)
+		}
+	}
+	return t, nil
+}
+
+func detectCargo(dir string) (*toolchain, error) {
+	if found, err := exists(dir, "Cargo.toml"); found == "" || err != nil {
+		return nil, err
+	}
+
+	return &toolchain{name: "cargo", stages: map[string]Command{
+		"install": argv("cargo", "fetch"),
+		"lint":    argv("cargo", "clippy", "--all-targets", "--", "-D", "warnings"),
+		"test":    argv("cargo", "test"),
+		"build":   argv("cargo", "build", "--release"),
+	}}, nil
+}
+
+// detectMake runs makefile targets named after scaffold stages.
+func detectMake(dir string) (*toolchain, error) {
+	path, err := FindMakefile(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	imported, _, err := ImportMakefile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &toolchain{name: "make", stages: make(map[string]Command)}
+	for _, stage := range scaffoldStages {
+		if _, ok := imported.Commands[stage]; ok {
+			t.stages[stage] = argv("make", stage)
+		}
+	}
+	return t, nil
+}
+
+var imageNameUnsafe = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+func detectDocker(dir string) (*Command, error) {
+	if found, err := exists(dir, "Dockerfile"); found == "" || err != nil {
+		return nil, err
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("abs %q: %w", dir, err)
+	}
+	image := strings.Trim(imageNameUnsafe.ReplaceAllString(strings.ToLower(filepath.Base(abs)), "-"), "-._")
+	if image == "" {
+		image = "app"
+	}
+
+	c := argv("docker", "build", "-t", image, ".")
+	return &c, nil
+}
@@ -0,0 +1,64 @@
+package fexec_test
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/roboslone/go-framework/v2/fexec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScaffold(t *testing.T) {
+	write := func(dir, name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	dir := filepath.Join(t.TempDir(), "My App")
+	require.NoError(t, os.Mkdir(dir, 0o755))
+
+	_, err := fexec.Scaffold(dir)
+	require.ErrorContains(t, err, "no known project files")
+
+	write(dir, "go.mod", "module example.com/app\n")
+	write(dir, ".golangci.yml", "")
+
+	cfg, err := fexec.Scaffold(dir)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"install", "lint", "test", "build", "ci"}, slices.Collect(maps.Keys(cfg.Commands)))
+	require.Equal(t, []string{"golangci-lint", "run"}, cfg.Commands["lint"].Command)
+	require.Equal(t, []string{"install"}, cfg.Commands["test"].DependsOn)
+	require.Equal(t, []string{"lint", "test", "build"}, cfg.Commands["ci"].DependsOn)
+
+	// makefile targets replace stages of other toolchains, stages provided by several toolchains are namespaced
+	write(dir, "Makefile", "test:\n\tgo test -race ./...\n")
+	write(dir, "package.json", `{"scripts": {"lint": "eslint ."}}`)
+	write(dir, "pnpm-lock.yaml", "")
+	write(dir, "Dockerfile", "FROM scratch\n")
+
+	cfg, err = fexec.Scaffold(dir)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{
+		"go:install", "node:install", "install",
+		"go:lint", "node:lint", "lint",
+		"test", "build", "docker", "ci",
+	}, slices.Collect(maps.Keys(cfg.Commands)))
+	require.Equal(t, []string{"make", "test"}, cfg.Commands["test"].Command)
+	require.Equal(t, []string{"install"}, cfg.Commands["test"].DependsOn)
+	require.Equal(t, []string{"go:install"}, cfg.Commands["build"].DependsOn)
+	require.Equal(t, []string{"pnpm", "run", "lint"}, cfg.Commands["node:lint"].Command)
+	require.Equal(t, []string{"node:install"}, cfg.Commands["node:lint"].DependsOn)
+	require.Equal(t, []string{"go:lint", "node:lint"}, cfg.Commands["lint"].DependsOn)
+	require.Equal(t, []string{"docker", "build", "-t", "my-app", "."}, cfg.Commands["docker"].Command)
+	require.Equal(t, []string{"lint", "test", "build", "docker"}, cfg.Commands["ci"].DependsOn)
+
+	// generated config is valid
+	data, err := fexec.EncodeConfig(cfg)
+	require.NoError(t, err)
+	write(dir, ".fexec.yaml", string(data))
+
+	_, err = fexec.ParseConfig(filepath.Join(dir, ".fexec.yaml"))
+	require.NoError(t, err)
+}
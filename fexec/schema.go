@@ -0,0 +1,101 @@
+package fexec
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/roboslone/go-framework/v2"
+)
+
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema returns JSON Schema of config file, generated from `CommandConfig`.
+func Schema() map[string]any {
+	g := &schemaGenerator{defs: make(map[string]any)}
+	root := g.object(reflect.TypeFor[CommandConfig]())
+	root["$schema"] = schemaDialect
+	root["title"] = "fexec config"
+	root["$defs"] = g.defs
+	return root
+}
+
+type schemaGenerator struct {
+	defs map[string]any
+}
+
+var (
+	durationType = reflect.TypeFor[time.Duration]()
+	byteSizeType = reflect.TypeFor[framework.ByteSize]()
+	inputType    = reflect.TypeFor[framework.Input]()
+)
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]any {
+	switch {
+	case t == reflect.TypeFor[Var]():
+		return g.ref("Var", func() map[string]any {
+			return map[string]any{"oneOf": []any{
+				map[string]any{"type": "string"},
+				g.object(t),
+			}}
+		})
+	case t == inputType:
+		return g.ref("Input", func() map[string]any {
+			return map[string]any{"oneOf": []any{
+				map[string]any{"type": "string"},
+				g.object(t),
+			}}
+		})
+	case t == durationType:
+		return map[string]any{"type": "string", "pattern": `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`}
+	case t == byteSizeType:
+		return map[string]any{"oneOf": []any{
+			map[string]any{"type": "integer", "minimum": 0},
+			map[string]any{"type": "string", "pattern": `^[0-9]+(\.[0-9]+)?\s*([KkMmGgTt]([Ii][Bb]?|[Bb])?|[Bb])?$`},
+		}}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		return map[string]any{"type": "integer"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		name := t.Name()
+		return g.ref(name[strings.LastIndex(name, ".")+1:], func() map[string]any { return g.object(t) })
+	default:
+		panic(fmt.Sprintf("schema: unsupported type: %s", t))
+	}
+}
+
+// ref returns a reference to the named definition, defining it first if needed.
+func (g *schemaGenerator) ref(name string, define func() map[string]any) map[string]any {
+	if _, ok := g.defs[name]; !ok {
+		g.defs[name] = nil // recursive types refer to the definition in progress
+		g.defs[name] = define()
+	}
+	return map[string]any{"$ref": "#/$defs/" + name}
+}
+
+// object describes struct fields by their yaml tags, inline fields are flattened.
+func (g *schemaGenerator) object(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	for name, field := range yamlFields(t) {
+		properties[name] = g.schema(field)
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"patternProperties":    map[string]any{"^" + extensionPrefix: map[string]any{}},
+		"additionalProperties": false,
+	}
+}
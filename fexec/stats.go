@@ -0,0 +1,106 @@
+package fexec
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/roboslone/go-framework/v2"
+)
+
+// ModuleStats summarizes recorded runs of a module, skipped runs are not counted.
+type ModuleStats struct {
+	Module   string
+	Runs     int
+	Failures int
+
+	// FlakyFailures are failures at commits the module also succeeded at.
+	// Runs with uncommitted changes are not considered.
+	FlakyFailures int
+
+	// P50 and P95 are percentiles of successful runs duration.
+	P50 time.Duration
+	P95 time.Duration
+
+	// MaxRSS is the largest peak RSS among all runs, failed ones included.
+	MaxRSS framework.ByteSize
+}
+
+// FlakyRate is a share of runs, that failed while the module succeeded at the same commit.
+func (s ModuleStats) FlakyRate() float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+	return float64(s.FlakyFailures) / float64(s.Runs)
+}
+
+// ComputeStats summarizes given runs per module, sorted by module name.
+func ComputeStats(runs []HistoryRun) []ModuleStats {
+	type commit struct {
+		module, sha string
+	}
+
+	stats := make(map[string]*ModuleStats)
+	durations := make(map[string][]time.Duration)
+	succeeded := make(map[commit]bool)
+	failed := make(map[commit]int)
+
+	for _, run := range runs {
+		for _, m := range run.Modules {
+			if m.Status == framework.StatusSkipped {
+				continue
+			}
+
+			s, ok := stats[m.Module]
+			if !ok {
+				s = &ModuleStats{Module: m.Module}
+				stats[m.Module] = s
+			}
+			s.Runs++
+			if m.Usage != nil {
+				s.MaxRSS = max(s.MaxRSS, m.Usage.MaxRSS)
+			}
+
+			c := commit{m.Module, run.GitSHA}
+			clean := run.GitSHA != "" && !run.Dirty
+			if m.Status == framework.StatusFailed {
+				s.Failures++
+				if clean {
+					failed[c]++
+				}
+				continue
+			}
+
+			durations[m.Module] = append(durations[m.Module], m.Duration)
+			if clean {
+				succeeded[c] = true
+			}
+		}
+	}
+
+	for c, n := range failed {
+		if succeeded[c] {
+			stats[c.module].FlakyFailures += n
+		}
+	}
+
+	result := make([]ModuleStats, 0, len(stats))
+	for _, name := range slices.Sorted(maps.Keys(stats)) {
+		s := stats[name]
+		slices.Sort(durations[name])
+		s.P50 = percentile(durations[name], 0.5)
+		s.P95 = percentile(durations[name], 0.95)
+		result = append(result, *s)
+	}
+	return result
+}
+
+// percentile returns nearest-rank percentile of sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(i, 0)]
+}
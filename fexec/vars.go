@@ -0,0 +1,145 @@
+package fexec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Var is a value of config variable: either a string, that can reference other variables,
+// or an output of a shell command, e.g. `GIT_SHA: {sh: "git rev-parse HEAD"}`.
+type Var struct {
+	Value string `yaml:"value"`
+	Sh    string `yaml:"sh"`
+
+	// dir is a location of config defining the variable, shell commands are run there.
+	dir string
+}
+
+func (v *Var) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&v.Value)
+	}
+
+	type plain Var
+	return node.Decode((*plain)(v))
+}
+
+// ParseOverrides splits command line arguments into module names and variable overrides (`NAME=value`).
+func ParseOverrides(args []string) ([]string, map[string]string) {
+	names := make([]string, 0, len(args))
+	overrides := make(map[string]string)
+
+	for _, arg := range args {
+		if k, v, ok := strings.Cut(arg, "="); ok && k != "" {
+			overrides[k] = v
+			continue
+		}
+		names = append(names, arg)
+	}
+
+	return names, overrides
+}
+
+// ResolveVars computes values of all given variables.
+// Overrides take precedence over definitions.
+func ResolveVars(ctx context.Context, defs map[string]Var, overrides map[string]string) (map[string]string, error) {
+	r := &varResolver{
+		ctx:    ctx,
+		defs:   defs,
+		values: maps.Clone(overrides),
+	}
+	if r.values == nil {
+		r.values = make(map[string]string)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(defs)) {
+		if _, err := r.resolve(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return r.values, nil
+}
+
+// ExpandVars substitutes given variables in `s`, falling back to process environment.
+// References to outputs of other modules (`${module.KEY}`) are kept, as they're only known at runtime.
+func ExpandVars(s string, vars map[string]string) string {
+	return os.Expand(s, func(name string) string {
+		if v, ok := vars[name]; ok {
+			return v
+		}
+		if strings.Contains(name, ".") {
+			return "${" + name + "}"
+		}
+		return os.Getenv(name)
+	})
+}
+
+type varResolver struct {
+	ctx       context.Context
+	defs      map[string]Var
+	values    map[string]string
+	resolving []string
+}
+
+func (r *varResolver) resolve(name string) (string, error) {
+	if v, ok := r.values[name]; ok {
+		return v, nil
+	}
+
+	def, ok := r.defs[name]
+	if !ok {
+		return os.Getenv(name), nil
+	}
+
+	if slices.Contains(r.resolving, name) {
+		return "", fmt.Errorf("variable cycle: %s -> %s", strings.Join(r.resolving, " -> "), name)
+	}
+	r.resolving = append(r.resolving, name)
+	defer func() {
+		r.resolving = r.resolving[:len(r.resolving)-1]
+	}()
+
+	var err error
+	expand := func(s string) string {
+		return os.Expand(s, func(ref string) string {
+			v, refErr := r.resolve(ref)
+			if refErr != nil && err == nil {
+				err = refErr
+			}
+			return v
+		})
+	}
+
+	value := expand(def.Value)
+	if def.Sh != "" {
+		script := expand(def.Sh)
+		if err != nil {
+			return "", err
+		}
+
+		out := bytes.Buffer{}
+		cmd := exec.CommandContext(r.ctx, "sh", "-c", script)
+		cmd.Dir = def.dir
+		cmd.Stdout = &out
+		cmd.Stderr = os.Stderr
+		if err = cmd.Run(); err != nil {
+			return "", fmt.Errorf("variable %q: $ %s: %w", name, script, err)
+		}
+		value = strings.TrimSpace(out.String())
+	}
+	if err != nil {
+		return "", err
+	}
+
+	r.values[name] = value
+	return value, nil
+}
@@ -0,0 +1,151 @@
+package fexec_test
+
+import (
+	"testing"
+
+	"github.com/roboslone/go-framework/v2/fexec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveVars(t *testing.T) {
+	t.Setenv("FEXEC_TEST_ENV", "env")
+
+	for _, tc := range []struct {
+		name      string
+		defs      map[string]fexec.Var
+		overrides map[string]string
+		expected  map[string]string
+		err       string
+	}{
+		{
+			name:     "values",
+			defs:     map[string]fexec.Var{"A": {Value: "a"}, "B": {Value: ""}},
+			expected: map[string]string{"A": "a", "B": ""},
+		},
+		{
+			name:     "references",
+			defs:     map[string]fexec.Var{"A": {Value: "${B}-$C"}, "B": {Value: "b"}, "C": {Value: "${B}c"}},
+			expected: map[string]string{"A": "b-bc", "B": "b", "C": "bc"},
+		},
+		{
+			name:     "environment",
+			defs:     map[string]fexec.Var{"A": {Value: "${FEXEC_TEST_ENV}"}, "B": {Value: "${FEXEC_TEST_MISSING}"}},
+			expected: map[string]string{"A": "env", "B": ""},
+		},
+		{
+			name:     "sh",
+			defs:     map[string]fexec.Var{"A": {Sh: "echo '  a  '"}, "B": {Sh: "printf '%s-x\\n' ${A}"}},
+			expected: map[string]string{"A": "a", "B": "a-x"},
+		},
+		{
+			name: "sh failure",
+			defs: map[string]fexec.Var{"A": {Sh: "exit 3"}},
+			err:  `variable "A": $ exit 3: exit status 3`,
+		},
+		{
+			name:      "overrides",
+			defs:      map[string]fexec.Var{"A": {Value: "a"}, "B": {Value: "${A}b"}},
+			overrides: map[string]string{"A": "x", "C": "c"},
+			expected:  map[string]string{"A": "x", "B": "xb", "C": "c"},
+		},
+		{
+			// the command would fail if it ran
+			name:      "overridden sh doesn't run",
+			defs:      map[string]fexec.Var{"A": {Sh: "exit 1"}, "B": {Value: "${A}b"}},
+			overrides: map[string]string{"A": "a"},
+			expected:  map[string]string{"A": "a", "B": "ab"},
+		},
+		{
+			name: "cycle",
+			defs: map[string]fexec.Var{"A": {Value: "${B}"}, "B": {Value: "${C}"}, "C": {Value: "${A}"}},
+			err:  "variable cycle: A -> B -> C -> A",
+		},
+		{
+			name: "self reference",
+			defs: map[string]fexec.Var{"A": {Sh: "echo ${A}"}},
+			err:  "variable cycle: A -> A",
+		},
+		{
+			name:      "overridden cycle",
+			defs:      map[string]fexec.Var{"A": {Value: "${B}"}, "B": {Value: "${A}"}},
+			overrides: map[string]string{"B": "b"},
+			expected:  map[string]string{"A": "b", "B": "b"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			vars, err := fexec.ResolveVars(t.Context(), tc.defs, tc.overrides)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, vars)
+		})
+	}
+}
+
+func TestParseOverrides(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		args      []string
+		names     []string
+		overrides map[string]string
+	}{
+		{name: "empty", names: []string{}, overrides: map[string]string{}},
+		{
+			name:      "names and overrides",
+			args:      []string{"build", "GOOS=linux", "test", "EMPTY="},
+			names:     []string{"build", "test"},
+			overrides: map[string]string{"GOOS": "linux", "EMPTY": ""},
+		},
+		{
+			name:      "value with separator",
+			args:      []string{"FLAGS=-X main.version=1.0"},
+			names:     []string{},
+			overrides: map[string]string{"FLAGS": "-X main.version=1.0"},
+		},
+		{
+			name:      "matrix expansions and namespaces",
+			args:      []string{"build[arm64,linux]", "web:test", "build.GOOS=darwin"},
+			names:     []string{"build[arm64,linux]", "web:test"},
+			overrides: map[string]string{"build.GOOS": "darwin"},
+		},
+		{
+			name:      "empty name",
+			args:      []string{"=value"},
+			names:     []string{"=value"},
+			overrides: map[string]string{},
+		},
+		{
+			name:      "last override wins",
+			args:      []string{"A=1", "A=2"},
+			names:     []string{},
+			overrides: map[string]string{"A": "2"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			names, overrides := fexec.ParseOverrides(tc.args)
+			require.Equal(t, tc.names, names)
+			require.Equal(t, tc.overrides, overrides)
+		})
+	}
+}
+
+func TestExpandVars(t *testing.T) {
+	t.Setenv("FEXEC_TEST_ENV", "env")
+	vars := map[string]string{"A": "a", "EMPTY": "", "FEXEC_TEST_ENV": "var"}
+
+	for s, expected := range map[string]string{
+		"plain":                 "plain",
+		"$A ${A}":               "a a",
+		"[${EMPTY}]":            "[]",
+		"${FEXEC_TEST_ENV}":     "var",
+		"${FEXEC_TEST_MISSING}": "",
+		"${build.VERSION}":      "${build.VERSION}",
+		"${A}-${web:build.V}":   "a-${web:build.V}",
+	} {
+		require.Equal(t, expected, fexec.ExpandVars(s, vars), s)
+	}
+
+	require.Equal(t, "env", fexec.ExpandVars("$FEXEC_TEST_ENV", nil), "process environment is a fallback")
+}
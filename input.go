@@ -0,0 +1,77 @@
+package framework
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Input is stdin of a command: literal Text, contents of File, or stdin of the process, if Inherit is set.
+// When decoded from text (e.g. a YAML scalar), `inherit` sets Inherit, anything else is Text.
+type Input struct {
+	Text string `yaml:"text"`
+
+	// File is substituted with variables, relative path is resolved against directory of the command.
+	File string `yaml:"file"`
+
+	// Inherit passes stdin of the process. If it's a terminal, the command runs interactively,
+	// see `CommandModule.Interactive`.
+	Inherit bool `yaml:"inherit"`
+}
+
+func (i *Input) UnmarshalText(text []byte) error {
+	if string(text) == "inherit" {
+		*i = Input{Inherit: true}
+	} else {
+		*i = Input{Text: string(text)}
+	}
+	return nil
+}
+
+// Validate reports conflicting options.
+func (i *Input) Validate() error {
+	set := 0
+	for _, ok := range []bool{i.Text != "", i.File != "", i.Inherit} {
+		if ok {
+			set++
+		}
+	}
+	if set > 1 {
+		return errors.New("text, file and inherit are mutually exclusive")
+	}
+	return nil
+}
+
+// inheritsTerminal reports whether the input is stdin of the process, which is a terminal.
+func (i *Input) inheritsTerminal() bool {
+	return i != nil && i.Inherit && term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// open returns a reader for a single run of a command in `dir`, or nil if input is empty.
+func (i *Input) open(dir string, expand func(string) string) (io.Reader, func() error, error) {
+	noop := func() error { return nil }
+
+	switch {
+	case i == nil:
+		return nil, noop, nil
+	case i.Inherit:
+		return os.Stdin, noop, nil
+	case i.File != "":
+		path := expand(i.File)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("opening stdin: %w", err)
+		}
+		return f, f.Close, nil
+	default:
+		return strings.NewReader(i.Text), noop, nil
+	}
+}
@@ -0,0 +1,99 @@
+// Package git queries local git repository using `git` command.
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// ChangedFiles returns paths of files changed since `base`, relative to repository root.
+// Both committed and uncommitted (including untracked) changes are reported.
+// Changes are computed against the merge base of `base` and HEAD, so changes made on `base` are not included.
+func ChangedFiles(ctx context.Context, dir, base string) ([]string, error) {
+	if base == "" {
+		base = "HEAD"
+	}
+
+	mergeBase, err := run(ctx, dir, "merge-base", base, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := run(ctx, dir, "diff", "--name-only", "--no-renames", strings.TrimSpace(mergeBase))
+	if err != nil {
+		return nil, err
+	}
+
+	untracked, err := run(ctx, dir, "ls-files", "--others", "--exclude-standard", "--full-name")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, line := range strings.Split(diff+untracked, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+
+	slices.Sort(files)
+	return slices.Compact(files), nil
+}
+
+// Root returns absolute path to the root of repository containing `dir`.
+func Root(ctx context.Context, dir string) (string, error) {
+	out, err := run(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Head returns SHA of the commit checked out in repository containing `dir`.
+func Head(ctx context.Context, dir string) (string, error) {
+	out, err := run(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Dirty reports whether repository containing `dir` has uncommitted changes, including untracked files.
+func Dirty(ctx context.Context, dir string) (bool, error) {
+	out, err := run(ctx, dir, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) != "", nil
+}
+
+// AddWorktree checks out HEAD of repository containing `dir` into a new detached worktree at `path`.
+func AddWorktree(ctx context.Context, dir, path string) error {
+	_, err := run(ctx, dir, "worktree", "add", "--detach", "--quiet", path, "HEAD")
+	return err
+}
+
+// RemoveWorktree removes worktree at `path` from repository containing `dir`, discarding its changes.
+func RemoveWorktree(ctx context.Context, dir, path string) error {
+	_, err := run(ctx, dir, "worktree", "remove", "--force", path)
+	return err
+}
+
+func run(ctx context.Context, dir string, args ...string) (string, error) {
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,52 @@
+// Package glob matches slash-separated paths against patterns.
+package glob
+
+import (
+	"path"
+	"strings"
+)
+
+// Match reports whether `name` matches `pattern`.
+//
+// Pattern syntax is the same as in `path.Match`, with addition of `**`,
+// that matches any number of path segments (including zero), e.g. `docs/**` or `**/*.md`.
+func Match(pattern, name string) (bool, error) {
+	return match(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func match(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			if len(rest) == 0 {
+				return true, nil
+			}
+			for i := range len(name) + 1 {
+				if ok, err := match(rest, name[i:]); ok || err != nil {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+
+		if len(name) == 0 {
+			return false, nil
+		}
+		if ok, err := path.Match(pattern[0], name[0]); !ok || err != nil {
+			return false, err
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0, nil
+}
+
+// MatchAny reports whether `name` matches any of `patterns`.
+func MatchAny(patterns []string, name string) (bool, error) {
+	for _, p := range patterns {
+		if ok, err := Match(p, name); ok || err != nil {
+			return ok, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,31 @@
+package glob_test
+
+import (
+	"testing"
+
+	"github.com/roboslone/go-framework/v2/internal/glob"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"docs/**", "docs/a.md", true},
+		{"docs/**", "docs/a/b/c.md", true},
+		{"docs/**", "src/docs/a.md", false},
+		{"**/*.md", "README.md", true},
+		{"**/*.md", "a/b/README.md", true},
+		{"**/*.md", "a/b/main.go", false},
+		{"src/**/test/*.go", "src/test/a.go", true},
+		{"src/**/test/*.go", "src/a/b/test/a.go", true},
+		{"*.go", "a/main.go", false},
+		{"services/*/go.mod", "services/api/go.mod", true},
+	} {
+		match, err := glob.Match(tc.pattern, tc.name)
+		require.NoError(t, err)
+		require.Equal(t, tc.match, match, "%s ~ %s", tc.pattern, tc.name)
+	}
+}
@@ -0,0 +1,55 @@
+package framework
+
+import (
+	"bytes"
+	"sync"
+)
+
+// lineWriter calls `emit` for each complete line written to it.
+// Lines overwritten using carriage returns (e.g. progress bars) are reduced to their final state.
+// Incomplete line is emitted on Flush.
+type lineWriter struct {
+	lock sync.Mutex
+	buf  []byte
+	emit func(string)
+}
+
+func newLineWriter(emit func(string)) *lineWriter {
+	return &lineWriter{emit: emit}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(string(overwrite(w.buf[:i])))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) Flush() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if len(w.buf) > 0 {
+		w.emit(string(overwrite(w.buf)))
+		w.buf = nil
+	}
+}
+
+// overwrite returns the last part of the line written after a carriage return.
+// Trailing carriage returns are ignored, as in CRLF line endings.
+func overwrite(line []byte) []byte {
+	line = bytes.TrimRight(line, "\r")
+	if i := bytes.LastIndexByte(line, '\r'); i >= 0 {
+		return line[i+1:]
+	}
+	return line
+}
@@ -0,0 +1,212 @@
+package framework
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// LogIndexName is a name of summary file written by `LogReporter`.
+	LogIndexName = "index.json"
+
+	logTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+)
+
+// LogReporter writes full output of each module to `<dir>/<module>.log`, each line prefixed with a timestamp.
+// Summary of all modules is written to `<dir>/index.json` on Close.
+type LogReporter struct {
+	dir     string
+	started time.Time
+
+	lock    sync.Mutex
+	files   map[string]*os.File
+	entries map[string]*LogEntry
+	err     error
+}
+
+// LogIndex is a content of the summary file written by `LogReporter`.
+type LogIndex struct {
+	Started  time.Time   `json:"started"`
+	Finished time.Time   `json:"finished"`
+	Modules  []*LogEntry `json:"modules"`
+}
+
+type LogEntry struct {
+	Module   string        `json:"module"`
+	Log      string        `json:"log,omitempty"`
+	Status   Status        `json:"status,omitempty"`
+	Started  time.Time     `json:"started,omitzero"`
+	Duration time.Duration `json:"duration"`
+	Reason   string        `json:"reason,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Usage    *Usage        `json:"usage,omitempty"`
+
+	// LogError is set if the log file couldn't be created, Log is empty then.
+	LogError string `json:"log_error,omitempty"`
+}
+
+func NewLogReporter(dir string) (*LogReporter, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating log dir: %w", err)
+	}
+
+	return &LogReporter{
+		dir:     dir,
+		started: time.Now(),
+		files:   make(map[string]*os.File),
+		entries: make(map[string]*LogEntry),
+	}, nil
+}
+
+func (r *LogReporter) Report(module string, event Event) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	// entry is recorded even if the log file couldn't be created
+	f, entry := r.open(module)
+
+	switch e := event.(type) {
+	case *StartEvent:
+		entry.Started = e.Time
+		for i, s := range e.Steps {
+			if len(e.Steps) > 1 {
+				r.write(f, e.Time, "", "# %s", s.label(i))
+			}
+			r.write(f, e.Time, "", "$ %s", s.String())
+		}
+
+	case *OutputEvent:
+		stream := "stdout"
+		if e.Stderr {
+			stream = "stderr"
+		}
+		if e.Step != "" {
+			stream = e.Step + " " + stream
+		}
+		r.write(f, e.Time, stream, "%s", e.Line)
+
+	case *WarningEvent:
+		r.write(f, e.Time, "warning", "%s", e.Message)
+
+	case *FinishEvent:
+		entry.Status = e.Status
+		entry.Duration = e.Duration
+		entry.Reason = e.Reason
+		if e.Status != StatusSkipped {
+			entry.Usage = &e.Usage
+		}
+		if e.Err != nil {
+			entry.Error = e.Err.Error()
+		}
+
+		switch {
+		case e.Status == StatusSkipped:
+			r.write(f, e.Time, "", "skipped: %s", e.Reason)
+		case e.Err != nil:
+			r.write(f, e.Time, "", "%s in %s: %s", e.Status, e.Duration.Round(time.Millisecond), e.Err)
+			r.write(f, e.Time, "", "usage: %s", e.Usage.Details())
+		default:
+			r.write(f, e.Time, "", "%s in %s", e.Status, e.Duration.Round(time.Millisecond))
+			r.write(f, e.Time, "", "usage: %s", e.Usage.Details())
+		}
+
+		r.closeFile(module)
+	}
+}
+
+// Close closes all log files and writes summary index.
+// Close returns the first error that occurred while writing logs.
+func (r *LogReporter) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for module := range r.files {
+		r.closeFile(module)
+	}
+
+	index := LogIndex{
+		Started:  r.started,
+		Finished: time.Now(),
+		Modules:  make([]*LogEntry, 0, len(r.entries)),
+	}
+	for _, e := range r.entries {
+		index.Modules = append(index.Modules, e)
+	}
+	slices.SortFunc(index.Modules, func(a, b *LogEntry) int {
+		return strings.Compare(a.Module, b.Module)
+	})
+
+	content, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding log index: %w", err)
+	}
+	if err = os.WriteFile(filepath.Join(r.dir, LogIndexName), content, 0o644); err != nil {
+		return fmt.Errorf("writing log index: %w", err)
+	}
+
+	return r.err
+}
+
+func (r *LogReporter) open(module string) (*os.File, *LogEntry) {
+	if entry, ok := r.entries[module]; ok {
+		return r.files[module], entry
+	}
+
+	entry := &LogEntry{Module: module, Log: LogFileName(module)}
+	r.entries[module] = entry
+
+	f, err := os.Create(filepath.Join(r.dir, entry.Log))
+	if err != nil {
+		err = fmt.Errorf("creating log file: %w", err)
+		r.fail(err)
+		entry.Log, entry.LogError = "", err.Error()
+		return nil, entry
+	}
+	r.files[module] = f
+	return f, entry
+}
+
+func (r *LogReporter) write(f *os.File, t time.Time, stream, format string, a ...any) {
+	if f == nil {
+		return
+	}
+	if stream != "" {
+		stream = "[" + stream + "] "
+	}
+	if _, err := fmt.Fprintf(f, "%s %s%s\n", t.Format(logTimeFormat), stream, fmt.Sprintf(format, a...)); err != nil {
+		r.fail(fmt.Errorf("writing log file: %w", err))
+	}
+}
+
+func (r *LogReporter) closeFile(module string) {
+	f, ok := r.files[module]
+	if !ok {
+		return
+	}
+	if err := f.Close(); err != nil {
+		r.fail(fmt.Errorf("closing log file: %w", err))
+	}
+	delete(r.files, module)
+}
+
+func (r *LogReporter) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// LogFileName returns name of the log file of a module, characters unsafe for file names are replaced.
+func LogFileName(module string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, module) + ".log"
+}
@@ -0,0 +1,155 @@
+package framework
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// SecretMask replaces secret values in everything reported by `CommandModule`.
+	SecretMask = "***"
+
+	// minSecretLength prevents masking of values short enough to garble unrelated output.
+	minSecretLength = 4
+)
+
+// masker redacts secret values and matches of mask patterns.
+// Both are matched within a single line, multi-line values are masked line by line.
+type masker struct {
+	values   []string
+	patterns []*regexp.Regexp
+}
+
+// masker collects values of Secrets from Vars, outputs and `env`, and compiles Mask patterns.
+// Names of secrets that are too short to be masked are returned along with the masker.
+func (m *CommandModule[State]) masker(env []string) (*masker, []string, error) {
+	r := &masker{}
+	var short []string
+
+	for _, name := range m.Secrets {
+		value, ok := m.lookup(name)
+		if !ok {
+			value, _ = lookupEnv(env, name)
+		}
+
+		for line := range strings.SplitSeq(value, "\n") {
+			line = strings.TrimSpace(line)
+			switch {
+			case line == "":
+			case len(line) < minSecretLength:
+				short = append(short, name)
+			default:
+				r.values = append(r.values, line)
+			}
+		}
+	}
+	// longer values first, so that values containing others are masked completely
+	slices.SortFunc(r.values, func(a, b string) int { return len(b) - len(a) })
+
+	for _, pattern := range m.Mask {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, nil, fmt.Errorf("mask %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	return r, slices.Compact(short), nil
+}
+
+func (r *masker) String(s string) string {
+	if r == nil || len(r.values) == 0 && len(r.patterns) == 0 {
+		return s
+	}
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		for _, v := range r.values {
+			line = strings.ReplaceAll(line, v, SecretMask)
+		}
+		for _, re := range r.patterns {
+			line = re.ReplaceAllLiteralString(line, SecretMask)
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (r *masker) Bytes(b []byte) []byte {
+	if r == nil || len(r.values) == 0 && len(r.patterns) == 0 {
+		return b
+	}
+	return []byte(r.String(string(b)))
+}
+
+// Error returns error with masked message, or the error itself if there's nothing to mask.
+func (r *masker) Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	if msg := r.String(err.Error()); msg != err.Error() {
+		return errors.New(msg)
+	}
+	return err
+}
+
+func (r *masker) Steps(steps []CommandStep) []CommandStep {
+	masked := make([]CommandStep, len(steps))
+	for i, s := range steps {
+		masked[i] = CommandStep{Name: s.Name, Script: r.String(s.Script)}
+		for _, arg := range s.Command {
+			masked[i].Command = append(masked[i].Command, r.String(arg))
+		}
+	}
+	return masked
+}
+
+// maskingReporter masks events before passing them to the underlying reporter.
+type maskingReporter struct {
+	reporter Reporter
+	masker   *masker
+}
+
+func (r *maskingReporter) Report(module string, event Event) {
+	switch e := event.(type) {
+	case *StartEvent:
+		masked := *e
+		masked.Steps = r.masker.Steps(e.Steps)
+		event = &masked
+
+	case *OutputEvent:
+		masked := *e
+		masked.Line = r.masker.String(e.Line)
+		event = &masked
+
+	case *WarningEvent:
+		masked := *e
+		masked.Message = r.masker.String(e.Message)
+		event = &masked
+
+	case *FinishEvent:
+		masked := *e
+		masked.Reason = r.masker.String(e.Reason)
+		masked.Err = r.masker.Error(e.Err)
+		masked.Steps = make([]StepResult, len(e.Steps))
+		for i, s := range e.Steps {
+			s.Command = r.masker.String(s.Command)
+			s.Output = r.masker.Bytes(s.Output)
+			s.Err = r.masker.Error(s.Err)
+			masked.Steps[i] = s
+		}
+		if e.Outputs != nil {
+			masked.Outputs = maps.Clone(e.Outputs)
+			for k, v := range masked.Outputs {
+				masked.Outputs[k] = r.masker.String(v)
+			}
+		}
+		event = &masked
+	}
+
+	r.reporter.Report(module, event)
+}
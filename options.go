@@ -7,3 +7,10 @@ func WithLogger[State any](logger Logger) ApplicationOption[State] {
 		a.logger = logger
 	}
 }
+
+// WithSelection narrows down modules run by the application, see `Selection`.
+func WithSelection[State any](selection Selection) ApplicationOption[State] {
+	return func(a *Application[State]) {
+		a.selection = selection
+	}
+}
@@ -0,0 +1,99 @@
+package framework
+
+import (
+	"bytes"
+	"maps"
+	"sync"
+)
+
+// OutputFileEnv is a name of environment variable, that contains path to a file
+// where command can write `KEY=VALUE` lines to publish its outputs.
+const OutputFileEnv = "FEXEC_OUTPUT"
+
+// Outputs stores values published by modules, so that their dependents can reference them.
+// Outputs are safe for concurrent use.
+type Outputs struct {
+	lock    sync.RWMutex
+	values  map[string]map[string]string
+	skipped map[string]bool
+}
+
+func NewOutputs() *Outputs {
+	return &Outputs{
+		values:  make(map[string]map[string]string),
+		skipped: make(map[string]bool),
+	}
+}
+
+// Set publishes given values of a module, merging them with previously published ones.
+func (o *Outputs) Set(module string, values map[string]string) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	delete(o.skipped, module)
+	if o.values[module] == nil {
+		o.values[module] = make(map[string]string, len(values))
+	}
+	maps.Copy(o.values[module], values)
+}
+
+// Get returns a value published by a module.
+func (o *Outputs) Get(module, key string) (string, bool) {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	v, ok := o.values[module][key]
+	return v, ok
+}
+
+// Skip records that a module was skipped, so it publishes nothing.
+func (o *Outputs) Skip(module string) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	delete(o.values, module)
+	o.skipped[module] = true
+}
+
+// Published reports whether a module ran and published its outputs, possibly none.
+func (o *Outputs) Published(module string) bool {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	_, ok := o.values[module]
+	return ok
+}
+
+// Skipped reports whether a module was skipped, see `Skip`.
+func (o *Outputs) Skipped(module string) bool {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	return o.skipped[module]
+}
+
+// All returns a copy of all values published by a module.
+func (o *Outputs) All(module string) map[string]string {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	return maps.Clone(o.values[module])
+}
+
+// syncBuffer is a `bytes.Buffer` safe for concurrent writes.
+type syncBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Bytes()
+}
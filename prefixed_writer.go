@@ -24,7 +24,7 @@ func (w *PrefixedWriter) Write(p []byte) (int, error) {
 			continue
 		}
 
-		msg := make([]byte, len(line)+len(w.prefix)+len(nl))
+		msg := make([]byte, 0, len(line)+len(w.prefix)+len(nl))
 		msg = append(msg, w.prefix...)
 		msg = append(msg, line...)
 		msg = append(msg, nl...)
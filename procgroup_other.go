@@ -0,0 +1,15 @@
+//go:build !unix
+
+package framework
+
+import (
+	"os"
+	"os/exec"
+)
+
+func setProcessGroup(*exec.Cmd) {}
+
+// signalProcessGroup kills the command, as process groups and signals are not supported.
+func signalProcessGroup(cmd *exec.Cmd, _ os.Signal) error {
+	return cmd.Process.Kill()
+}
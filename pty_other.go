@@ -0,0 +1,14 @@
+//go:build !unix
+
+package framework
+
+import (
+	"errors"
+	"io"
+	"os/exec"
+)
+
+// runInTerminal is not supported, command is not started.
+func runInTerminal(*exec.Cmd, io.Writer) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,58 @@
+//go:build unix
+
+package framework
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+)
+
+// ptyDrainTimeout limits waiting for terminal output after command exits,
+// as background processes may keep the terminal open.
+const ptyDrainTimeout = time.Second
+
+// runInTerminal runs command with a pseudo-terminal as its stdin, stdout and stderr, copying terminal output to `out`.
+// Command is run in a new session, which is also its own process group, so process group set up by
+// `setProcessGroup` is replaced.
+func runInTerminal(cmd *exec.Cmd, out io.Writer) error {
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		return err
+	}
+	defer ptmx.Close()
+
+	if err := pty.InheritSize(os.Stdout, ptmx); err != nil {
+		_ = pty.Setsize(ptmx, &pty.Winsize{Cols: 80, Rows: 24})
+	}
+
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = tty, tty, tty
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+
+	err = cmd.Start()
+	tty.Close()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// reading from terminal fails with EIO once all processes have closed it
+		_, _ = io.Copy(out, ptmx)
+	}()
+
+	err = cmd.Wait()
+
+	select {
+	case <-done:
+	case <-time.After(ptyDrainTimeout):
+		ptmx.Close()
+		<-done
+	}
+	return err
+}
@@ -0,0 +1,240 @@
+package framework
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Reporter receives events of command modules.
+// Reporter must be safe for concurrent use, as modules are run in parallel.
+type Reporter interface {
+	// Report is called with one of *StartEvent, *OutputEvent, *WarningEvent or *FinishEvent.
+	Report(module string, event Event)
+}
+
+type Event any
+
+type Status string
+
+const (
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusSkipped   Status = "skipped"
+)
+
+// StartEvent is reported when module starts running its command.
+type StartEvent struct {
+	Time  time.Time
+	Dir   string
+	Steps []CommandStep
+
+	// SourceDir is the original location of Dir, when the command runs in its copy, see `CommandModule.WorkdirCopy`.
+	SourceDir string
+
+	// Interactive is set for commands connected directly to the terminal, see `CommandModule.Interactive`.
+	Interactive bool
+}
+
+// OutputEvent is reported for each line of command output.
+type OutputEvent struct {
+	Time   time.Time
+	Step   string
+	Line   string
+	Stderr bool
+}
+
+// WarningEvent is reported for problems, that don't fail the module.
+type WarningEvent struct {
+	Time    time.Time
+	Message string
+}
+
+// FinishEvent is reported when module either finishes running its command or is skipped.
+type FinishEvent struct {
+	Time     time.Time
+	Status   Status
+	Duration time.Duration
+
+	// Reason is set for skipped modules.
+	Reason string
+
+	// Err is set for failed modules. Err may not belong to any of the steps, e.g. unexpected output.
+	Err error
+
+	Steps   []StepResult
+	Outputs map[string]string
+
+	// Usage is combined resource usage of all steps.
+	Usage Usage
+}
+
+// StepResult describes a single step run by a module.
+type StepResult struct {
+	Label    string
+	Command  string
+	Output   []byte
+	Err      error
+	Duration time.Duration
+	Usage    Usage
+}
+
+// Reporters fan events out to each of the reporters.
+type Reporters []Reporter
+
+func (rs Reporters) Report(module string, event Event) {
+	for _, r := range rs {
+		r.Report(module, event)
+	}
+}
+
+// ConsoleReporter prints a line for each finished module, along with command and output of failed ones.
+type ConsoleReporter struct {
+	// Verbose enables printing of commands & output for successful modules.
+	Verbose bool
+
+	// Live enables printing of command output as soon as it's produced, prefixed with module name.
+	Live bool
+
+	// Usage enables printing of resource usage in the line of each finished module.
+	Usage bool
+}
+
+func (r *ConsoleReporter) Report(module string, event Event) {
+	DefaultTerminal.Print(func() { r.print(module, event) })
+}
+
+func (r *ConsoleReporter) print(module string, event Event) {
+	switch e := event.(type) {
+	case *StartEvent:
+		switch {
+		case e.Interactive:
+			fmt.Printf(
+				"%s %s %s\n",
+				color.BlueString("↪︎"),
+				module,
+				color.BlackString("starting interactively..."),
+			)
+		case r.Verbose:
+			fmt.Printf(
+				"%s %s %s\n",
+				color.BlueString("↪︎"),
+				module,
+				color.BlackString("starting..."),
+			)
+		}
+
+	case *OutputEvent:
+		if r.Live {
+			prefix := module
+			if e.Step != "" {
+				prefix += "/" + e.Step
+			}
+
+			w := os.Stdout
+			if e.Stderr {
+				w = os.Stderr
+			}
+			line := e.Line
+			if strings.Contains(line, "\x1b[") {
+				// don't let colors of the command leak into following lines
+				line += "\x1b[0m"
+			}
+			fmt.Fprintf(w, "%s%s\n", color.BlackString("[%s] ", prefix), line)
+		}
+
+	case *WarningEvent:
+		fmt.Printf(
+			"%s %s %s\n",
+			color.YellowString("⚠"),
+			module,
+			color.BlackString(e.Message),
+		)
+
+	case *FinishEvent:
+		r.finish(module, e)
+	}
+}
+
+func (r *ConsoleReporter) finish(module string, e *FinishEvent) {
+	duration := e.Duration.Round(time.Millisecond).String()
+	if r.Usage {
+		duration += " " + e.Usage.String()
+	}
+
+	switch e.Status {
+	case StatusSkipped:
+		fmt.Printf(
+			"%s %s %s\n",
+			color.YellowString("↷"),
+			module,
+			color.BlackString("skipped: %s", e.Reason),
+		)
+		return
+
+	case StatusFailed:
+		fmt.Printf(
+			"%s %s %s\n",
+			color.RedString("❌"),
+			module,
+			color.BlackString(duration),
+		)
+
+	default:
+		fmt.Printf(
+			"%s %s %s\n",
+			color.GreenString("✓"),
+			module,
+			color.BlackString(duration),
+		)
+	}
+
+	// error not caused by any of the steps, e.g. unexpected output
+	var moduleErr error
+	if e.Err != nil && !slices.ContainsFunc(e.Steps, func(s StepResult) bool { return s.Err != nil }) {
+		moduleErr = e.Err
+	}
+
+	for _, s := range e.Steps {
+		if !r.Verbose && s.Err == nil && (moduleErr == nil || len(s.Output) == 0) {
+			continue
+		}
+
+		if len(e.Steps) > 1 {
+			color.Black("# %s %s", s.Label, s.Duration.Round(time.Millisecond))
+		}
+		color.Black("$ %s", s.Command)
+
+		if s.Err != nil {
+			color.Red(s.Err.Error())
+		}
+		if r.Live || len(s.Output) == 0 {
+			continue
+		}
+		if s.Err != nil {
+			fmt.Println(string(s.Output))
+		} else {
+			if r.Verbose {
+				fmt.Println()
+			}
+			color.Black(string(s.Output))
+		}
+	}
+
+	if moduleErr != nil {
+		color.Red(moduleErr.Error())
+	}
+
+	if r.Verbose {
+		color.Black("usage: %s", e.Usage.Details())
+		for _, k := range slices.Sorted(maps.Keys(e.Outputs)) {
+			color.Black("→ %s=%s", k, e.Outputs[k])
+		}
+		fmt.Println()
+	}
+}
@@ -0,0 +1,68 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// SignalCause is a cause of context cancellation by a signal, see `NotifyContext`.
+// Command modules forward the signal to their process groups.
+type SignalCause struct {
+	Signal os.Signal
+
+	// killed is closed once another signal is received, command modules kill their process groups then.
+	killed chan struct{}
+}
+
+func (c *SignalCause) Error() string {
+	return fmt.Sprintf("received signal: %s", c.Signal)
+}
+
+// NotifyContext is like `signal.NotifyContext`, except the context is cancelled with `SignalCause`.
+// Signals are handled until the returned function is called: a consequent signal doesn't terminate the process,
+// instead command modules kill their process groups without waiting for KillGrace.
+func NotifyContext(parent context.Context, signals ...os.Signal) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	stop := make(chan struct{})
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+
+	go func() {
+		defer signal.Stop(ch)
+
+		var cause *SignalCause
+		for {
+			select {
+			case sig := <-ch:
+				switch {
+				case cause == nil:
+					cause = &SignalCause{Signal: sig, killed: make(chan struct{})}
+					cancel(cause)
+				case !isClosed(cause.killed):
+					close(cause.killed)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() { close(stop) })
+		cancel(context.Canceled)
+	}
+}
+
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,43 @@
+//go:build unix
+
+package framework_test
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	framework "github.com/roboslone/go-framework/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyContext(t *testing.T) {
+	ctx, cancel := framework.NotifyContext(t.Context(), syscall.SIGUSR1)
+	defer cancel()
+
+	app := framework.NewApplication[TestState](t.Name(), framework.Modules{
+		"cmd": &framework.CommandModule[TestState]{
+			// ignored signal is inherited by sleep
+			Script:    `trap '' USR1; sleep 60 & wait`,
+			KillGrace: time.Minute,
+		},
+	})
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+		time.Sleep(300 * time.Millisecond)
+		assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	}()
+
+	started := time.Now()
+	require.Error(t, app.Run(ctx, t.Context(), &TestState{}, "cmd"))
+	require.Less(t, time.Since(started), 5*time.Second, "process group is killed on the second signal")
+
+	cause := (*framework.SignalCause)(nil)
+	require.ErrorAs(t, context.Cause(ctx), &cause)
+	require.Equal(t, syscall.SIGUSR1, cause.Signal)
+}
@@ -0,0 +1,73 @@
+package framework
+
+import (
+	"context"
+	"sync"
+)
+
+// Terminal arbitrates the process terminal between interactive commands and reporters:
+// interactive commands acquire it one at a time, reporters defer their output until it's released.
+type Terminal struct {
+	exclusive chan struct{}
+
+	lock    sync.Mutex
+	held    bool
+	pending []func()
+}
+
+// DefaultTerminal is shared by command modules and reporters of the process.
+var DefaultTerminal = NewTerminal()
+
+func NewTerminal() *Terminal {
+	return &Terminal{exclusive: make(chan struct{}, 1)}
+}
+
+// Acquire waits for exclusive access to the terminal. Until released, `Print` defers output.
+func (t *Terminal) Acquire(ctx context.Context) (release func(), err error) {
+	select {
+	case t.exclusive <- struct{}{}:
+	case <-ctx.Done():
+		return nil, context.Cause(ctx)
+	}
+
+	t.lock.Lock()
+	t.held = true
+	t.lock.Unlock()
+
+	return sync.OnceFunc(func() {
+		t.lock.Lock()
+		for _, f := range t.pending {
+			f()
+		}
+		t.pending, t.held = nil, false
+		t.lock.Unlock()
+
+		<-t.exclusive
+	}), nil
+}
+
+// Print calls `f`, that writes to the terminal, right away or once the terminal is released.
+// Calls are serialized, so output of different reporters doesn't interleave.
+func (t *Terminal) Print(f func()) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.held {
+		t.pending = append(t.pending, f)
+		return
+	}
+	f()
+}
+
+// TryPrint calls `f` unless the terminal is acquired, reporting whether it was called.
+// It's meant for output, that is redrawn anyway, e.g. progress.
+func (t *Terminal) TryPrint(f func()) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.held {
+		return false
+	}
+	f()
+	return true
+}
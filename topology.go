@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"slices"
+	"time"
 
 	mapset "github.com/deckarep/golang-set/v2"
 	"github.com/stevenle/topsort/v2"
@@ -87,5 +88,115 @@ func (a *Application[State]) BuildTopology(ctx context.Context, requested ...str
 		}
 	}
 
-	return t, nil
+	return t.Select(a.selection)
+}
+
+// Selection narrows down modules of the topology.
+// Zero value selects requested modules along with all of their dependencies.
+type Selection struct {
+	// Only selects requested modules without their dependencies.
+	Only bool
+
+	// DependenciesOnly selects dependencies of requested modules, without the modules themselves.
+	DependenciesOnly bool
+
+	// From selects given modules along with modules depending on them.
+	From []string
+
+	// Modules, if not empty, restricts selection to given modules, e.g. those affected by changes.
+	Modules []string
+}
+
+// Select returns topology restricted to selected modules, dependencies on modules left out are dropped.
+func (t *Topology) Select(s Selection) (*Topology, error) {
+	if s.Only && s.DependenciesOnly {
+		return nil, fmt.Errorf("only and dependencies only selections are mutually exclusive")
+	}
+	if !s.Only && !s.DependenciesOnly && len(s.From) == 0 && len(s.Modules) == 0 {
+		return t, nil
+	}
+
+	selected := mapset.NewSet(t.OrderedModuleNames...)
+	if s.Only {
+		selected = selected.Intersect(mapset.NewSet(t.RequestedModuleNames...))
+	}
+	if s.DependenciesOnly {
+		dependencies := mapset.NewSet[string]()
+		for _, name := range t.RequestedModuleNames {
+			dependencies.Append(t.FullDependencies[name]...)
+		}
+		selected = selected.Intersect(dependencies)
+	}
+	if len(s.From) > 0 {
+		downstream := mapset.NewSet[string]()
+		for _, from := range s.From {
+			if !slices.Contains(t.OrderedModuleNames, from) {
+				return nil, fmt.Errorf("module is not part of the topology: %q", from)
+			}
+			downstream.Add(from)
+			for _, name := range t.OrderedModuleNames {
+				if slices.Contains(t.FullDependencies[name], from) {
+					downstream.Add(name)
+				}
+			}
+		}
+		selected = selected.Intersect(downstream)
+	}
+	if len(s.Modules) > 0 {
+		selected = selected.Intersect(mapset.NewSet(s.Modules...))
+	}
+	if selected.IsEmpty() {
+		return nil, fmt.Errorf("no modules selected")
+	}
+
+	keep := func(names []string) []string {
+		return slices.DeleteFunc(slices.Clone(names), func(name string) bool { return !selected.Contains(name) })
+	}
+
+	r := &Topology{
+		RequestedModuleNames: keep(t.RequestedModuleNames),
+		Graph:                t.Graph,
+		OrderedModuleNames:   keep(t.OrderedModuleNames),
+		DirectDependencies:   make(map[string][]string, selected.Cardinality()),
+		FullDependencies:     make(map[string][]string, selected.Cardinality()),
+	}
+	for name := range selected.Iter() {
+		if deps, ok := t.DirectDependencies[name]; ok {
+			r.DirectDependencies[name] = keep(deps)
+		}
+		r.FullDependencies[name] = keep(t.FullDependencies[name])
+	}
+	return r, nil
+}
+
+// CriticalPath returns the most costly chain of dependent modules, along with its total cost.
+// With enough concurrency, the critical path bounds the wall-clock time of running the topology.
+func (t *Topology) CriticalPath(cost func(module string) time.Duration) ([]string, time.Duration) {
+	finish := make(map[string]time.Duration, len(t.OrderedModuleNames))
+	previous := make(map[string]string, len(t.OrderedModuleNames))
+
+	var last string
+	for _, name := range t.OrderedModuleNames {
+		var start time.Duration
+		for _, d := range t.DirectDependencies[name] {
+			if finish[d] > start || previous[name] == "" {
+				start = finish[d]
+				previous[name] = d
+			}
+		}
+
+		finish[name] = start + cost(name)
+		if last == "" || finish[name] > finish[last] {
+			last = name
+		}
+	}
+	if last == "" {
+		return nil, 0
+	}
+
+	path := []string{last}
+	for previous[path[0]] != "" {
+		path = slices.Insert(path, 0, previous[path[0]])
+	}
+	return path, finish[last]
 }
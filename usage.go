@@ -0,0 +1,120 @@
+package framework
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Usage is resource usage of a command, as reported by the operating system.
+// Usage includes descendants of the command, that were waited for.
+type Usage struct {
+	UserTime   time.Duration `json:"user_time"`
+	SystemTime time.Duration `json:"system_time"`
+
+	// MaxRSS is the peak resident set size of the largest process. Zero, if not supported by the platform.
+	MaxRSS ByteSize `json:"max_rss"`
+
+	// InBlock and OutBlock count file system inputs and outputs.
+	InBlock  int64 `json:"in_block"`
+	OutBlock int64 `json:"out_block"`
+}
+
+// CPU returns total CPU time.
+func (u Usage) CPU() time.Duration {
+	return u.UserTime + u.SystemTime
+}
+
+// Add returns usage of both runs, e.g. sequential steps: times and I/O are summed, peak RSS is maximum of the two.
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		UserTime:   u.UserTime + other.UserTime,
+		SystemTime: u.SystemTime + other.SystemTime,
+		MaxRSS:     max(u.MaxRSS, other.MaxRSS),
+		InBlock:    u.InBlock + other.InBlock,
+		OutBlock:   u.OutBlock + other.OutBlock,
+	}
+}
+
+// String returns short human-readable summary, e.g. `cpu 1.2s, rss 512MiB`.
+func (u Usage) String() string {
+	s := fmt.Sprintf("cpu %s", u.CPU().Round(time.Millisecond))
+	if u.MaxRSS > 0 {
+		s += fmt.Sprintf(", rss %s", u.MaxRSS)
+	}
+	return s
+}
+
+// Details returns all usage counters in human-readable form.
+func (u Usage) Details() string {
+	return fmt.Sprintf(
+		"user %s, system %s, max rss %s, fs in %d, fs out %d",
+		u.UserTime.Round(time.Millisecond), u.SystemTime.Round(time.Millisecond), u.MaxRSS, u.InBlock, u.OutBlock,
+	)
+}
+
+// ByteSize is a size in bytes, parsed from plain numbers or numbers with units, see `ParseByteSize`.
+type ByteSize int64
+
+var byteUnits = []struct {
+	suffix string
+	size   float64
+}{
+	// longer suffixes first
+	{"kib", 1 << 10}, {"mib", 1 << 20}, {"gib", 1 << 30}, {"tib", 1 << 40},
+	{"ki", 1 << 10}, {"mi", 1 << 20}, {"gi", 1 << 30}, {"ti", 1 << 40},
+	{"kb", 1e3}, {"mb", 1e6}, {"gb", 1e9}, {"tb", 1e12},
+	{"k", 1e3}, {"m", 1e6}, {"g", 1e9}, {"t", 1e12},
+	{"b", 1},
+}
+
+// ParseByteSize parses sizes like `1073741824`, `512MiB`, `1.5Gi` or `2GB`.
+// Units are case-insensitive: `KiB` (or `Ki`) and alike are powers of 1024, `KB` (or `K`) and alike are powers of 1000.
+func ParseByteSize(s string) (ByteSize, error) {
+	number, size := strings.TrimSpace(s), 1.0
+	lower := strings.ToLower(number)
+	for _, unit := range byteUnits {
+		if strings.HasSuffix(lower, unit.suffix) {
+			number, size = strings.TrimSpace(number[:len(number)-len(unit.suffix)]), unit.size
+			break
+		}
+	}
+
+	v, err := strconv.ParseFloat(number, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid size: %q", s)
+	}
+	return ByteSize(v * size), nil
+}
+
+func (s *ByteSize) UnmarshalText(text []byte) error {
+	v, err := ParseByteSize(string(text))
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
+
+// UnmarshalJSON accepts both numbers (as written by `json.Marshal`) and strings with units.
+func (s *ByteSize) UnmarshalJSON(data []byte) error {
+	if text, err := strconv.Unquote(string(data)); err == nil {
+		data = []byte(text)
+	}
+	return s.UnmarshalText(data)
+}
+
+// String returns size in the largest binary unit, e.g. `1.5GiB`.
+func (s ByteSize) String() string {
+	for _, unit := range []struct {
+		suffix string
+		size   ByteSize
+	}{{"TiB", 1 << 40}, {"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10}} {
+		if s >= unit.size {
+			return strconv.FormatFloat(math.Round(float64(s)/float64(unit.size)*10)/10, 'f', -1, 64) + unit.suffix
+		}
+	}
+	return strconv.FormatInt(int64(s), 10) + "B"
+}
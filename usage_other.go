@@ -0,0 +1,12 @@
+//go:build !unix
+
+package framework
+
+import "os"
+
+func processUsage(state *os.ProcessState) Usage {
+	return Usage{UserTime: state.UserTime(), SystemTime: state.SystemTime()}
+}
+
+// maxMemorySupported reports whether peak RSS is measured on this platform.
+const maxMemorySupported = false
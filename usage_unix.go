@@ -0,0 +1,30 @@
+//go:build unix
+
+package framework
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+func processUsage(state *os.ProcessState) Usage {
+	u := Usage{UserTime: state.UserTime(), SystemTime: state.SystemTime()}
+
+	rusage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return u
+	}
+
+	// max rss is reported in bytes on darwin, in kilobytes elsewhere
+	u.MaxRSS = ByteSize(rusage.Maxrss)
+	if runtime.GOOS != "darwin" && runtime.GOOS != "ios" {
+		u.MaxRSS *= 1 << 10
+	}
+	u.InBlock = int64(rusage.Inblock)
+	u.OutBlock = int64(rusage.Oublock)
+	return u
+}
+
+// maxMemorySupported reports whether peak RSS is measured on this platform.
+const maxMemorySupported = true
@@ -4,6 +4,7 @@ import (
 	"context"
 	"log"
 	"os"
+	"sync"
 	"testing"
 
 	framework "github.com/roboslone/go-framework/v2"
@@ -126,3 +127,28 @@ func isCleanable(m any) bool {
 	_, ok := m.(framework.Cleanable[TestState])
 	return ok
 }
+
+// eventRecorder is a reporter, that keeps all reported events.
+type eventRecorder struct {
+	lock   sync.Mutex
+	events []framework.Event
+}
+
+func (r *eventRecorder) Report(_ string, event framework.Event) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.events = append(r.events, event)
+}
+
+func (r *eventRecorder) finish() *framework.FinishEvent {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, e := range r.events {
+		if finish, ok := e.(*framework.FinishEvent); ok {
+			return finish
+		}
+	}
+	return nil
+}
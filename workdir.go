@@ -0,0 +1,175 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/roboslone/go-framework/v2/internal/git"
+)
+
+// TmpDirEnv is a name of environment variable, that contains path to a temp directory of the command,
+// see `CommandModule.TmpDir`.
+const TmpDirEnv = "FEXEC_TMP"
+
+// createTempDir creates a directory, which name includes name of the module.
+func createTempDir(module, kind string) (string, error) {
+	module = strings.NewReplacer("/", "-", `\`, "-", ":", "-").Replace(module)
+	dir, err := os.MkdirTemp("", fmt.Sprintf("fexec-%s-%s-*", module, kind))
+	if err != nil {
+		return "", fmt.Errorf("creating %s dir: %w", kind, err)
+	}
+	return dir, nil
+}
+
+// workdirCopy is a throwaway copy of a repository, see `CommandModule.WorkdirCopy`.
+type workdirCopy struct {
+	// path is a root of the copy.
+	path string
+
+	// repo is a root of the original git repository, empty if the copy is not a worktree.
+	repo string
+}
+
+// copyWorkdir copies the repository containing `dir` and returns the copy along with location of `dir` within it.
+//
+// Git repositories are checked out as a detached worktree, uncommitted and untracked (but not ignored) files
+// are copied over it. Directories outside of git repositories are copied as is.
+func copyWorkdir(ctx context.Context, module, dir string) (*workdirCopy, string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, "", fmt.Errorf("abs %q: %w", dir, err)
+	}
+
+	root, err := git.Root(ctx, dir)
+	if err != nil {
+		path, err := createTempDir(module, "workdir")
+		if err != nil {
+			return nil, "", err
+		}
+		c := &workdirCopy{path: path}
+		if err = copyTree(dir, path); err != nil {
+			return nil, "", errors.Join(fmt.Errorf("copying %q: %w", dir, err), c.remove(ctx))
+		}
+		return c, path, nil
+	}
+
+	// `git rev-parse` resolves symlinks, so does location of `dir` within the repository
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, "", fmt.Errorf("resolving %q: %w", dir, err)
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil {
+		return nil, "", fmt.Errorf("rel %q: %w", dir, err)
+	}
+
+	path, err := createTempDir(module, "workdir")
+	if err != nil {
+		return nil, "", err
+	}
+	if err = git.AddWorktree(ctx, root, path); err != nil {
+		return nil, "", errors.Join(err, os.RemoveAll(path))
+	}
+	c := &workdirCopy{path: path, repo: root}
+
+	changed, err := git.ChangedFiles(ctx, root, "")
+	if err != nil {
+		return nil, "", errors.Join(err, c.remove(ctx))
+	}
+	for _, name := range changed {
+		if err = overlay(filepath.Join(root, name), filepath.Join(path, name)); err != nil {
+			return nil, "", errors.Join(fmt.Errorf("copying uncommitted changes: %w", err), c.remove(ctx))
+		}
+	}
+	return c, filepath.Join(path, rel), nil
+}
+
+// remove deletes the copy, along with its worktree registration.
+func (c *workdirCopy) remove(ctx context.Context) error {
+	if c.repo != "" {
+		if err := git.RemoveWorktree(ctx, c.repo, c.path); err == nil {
+			return nil
+		}
+	}
+	if err := os.RemoveAll(c.path); err != nil {
+		return fmt.Errorf("removing workdir copy: %w", err)
+	}
+	return nil
+}
+
+// overlay makes `dst` match `src`: copies it, or removes `dst` if `src` doesn't exist.
+func overlay(src, dst string) error {
+	if _, err := os.Lstat(src); errors.Is(err, os.ErrNotExist) {
+		return os.RemoveAll(dst)
+	}
+	if err := os.RemoveAll(dst); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	return copyTree(src, dst)
+}
+
+// copyTree copies a file, symlink or directory recursively, preserving permissions.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dst {
+			// destination is within the source, e.g. temp dir within copied directory
+			return filepath.SkipDir
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			// sockets, pipes and devices are not copied
+			return nil
+		}
+	})
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		return errors.Join(err, out.Close())
+	}
+	return out.Close()
+}